@@ -5,7 +5,9 @@ go 1.13
 require (
 	contrib.go.opencensus.io/exporter/jaeger v0.2.0
 	contrib.go.opencensus.io/exporter/zipkin v0.1.1
+	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/GotaX/logrus-aliyun-log-hook v1.0.0
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/aliyun/alibaba-cloud-sdk-go v1.60.354
 	github.com/aliyun/aliyun-oss-go-sdk v2.0.5+incompatible
 	github.com/aliyun/aliyun-tablestore-go-sdk v4.1.3+incompatible
@@ -13,11 +15,13 @@ require (
 	github.com/cenkalti/backoff v2.2.1+incompatible // indirect
 	github.com/gin-contrib/gzip v0.0.1
 	github.com/gin-gonic/gin v1.7.0
+	github.com/go-playground/validator/v10 v10.4.1
 	github.com/go-redis/redis/v7 v7.0.0-beta.6
 	github.com/go-redis/redis/v8 v8.0.0-beta.5
 	github.com/go-resty/resty/v2 v2.3.0
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gofiber/fiber/v2 v2.2.0
+	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/gogo/protobuf v1.3.1 // indirect
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	github.com/golang/protobuf v1.5.2
@@ -32,7 +36,8 @@ require (
 	github.com/sirupsen/logrus v1.4.2
 	github.com/streadway/amqp v0.0.0-20200108173154-1c71cc93ed71
 	github.com/uber/jaeger-client-go v2.22.1+incompatible // indirect
-	go.opencensus.io v0.22.3
+	github.com/valyala/fasthttp v1.17.0
+	go.opencensus.io v0.23.0
 	golang.org/x/net v0.0.0-20210316092652-d523dce5a7f4
 	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
 	golang.org/x/time v0.0.0-20191024005414-555d28b269f0 // indirect
@@ -40,5 +45,7 @@ require (
 	google.golang.org/api v0.17.0 // indirect
 	google.golang.org/genproto v0.0.0-20210416161957-9910b6c460de
 	google.golang.org/grpc v1.37.0
+	google.golang.org/protobuf v1.26.0
 	gopkg.in/ini.v1 v1.52.0 // indirect
+	gopkg.in/yaml.v2 v2.2.8
 )
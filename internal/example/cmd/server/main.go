@@ -1,12 +1,11 @@
 package main
 
 import (
-	"errors"
 	"net/http"
-	_ "net/http/pprof"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/GotaX/go-server-skeleton/internal/example/pkg/cfg"
 	"github.com/GotaX/go-server-skeleton/internal/example/pkg/srvrest"
 	"github.com/GotaX/go-server-skeleton/internal/example/pkg/srvrpc"
 	"github.com/GotaX/go-server-skeleton/pkg/endpoint"
@@ -15,14 +14,22 @@ import (
 )
 
 func main() {
+	var hc endpoint.HttpConfig
+	cfg.Http(&hc)
+
+	web := http.NewServeMux()
+	web.Handle("/", srvrest.Router())
+	web.Handle("/fiber/", http.StripPrefix("/fiber", endpoint.FiberHandler(srvrest.Fiber(), 0)))
+	for _, path := range []string{"/metrics", "/health", "/debug/pprof/"} {
+		web.Handle(path, metrics.Router())
+	}
+
 	err := endpoint.Run(
-		endpoint.Fiber("fiber", ":3000", srvrest.Fiber()),
-		endpoint.Http("rest", ":8080", srvrest.Router()),
-		endpoint.Http("metrics", ":8081", metrics.Router()),
-		endpoint.Grpc("app", ":8082", srvrpc.Server()),
+		// gRPC, gRPC-Web, gin, fiber and metrics on a single port
+		endpoint.Mux("app", ":8080", srvrpc.Server(), web, endpoint.HttpWithConfig(hc)),
 	)
-	if err != nil && !errors.Is(err, http.ErrServerClosed) {
-		logrus.WithError(err).Error("Shutdown")
+	if err != nil {
+		logrus.WithError(err).Fatal("Fail to run")
 	}
 	shutdown.Wait()
 }
@@ -9,7 +9,9 @@ import (
 
 	"github.com/GotaX/go-server-skeleton/pkg/cfg"
 	"github.com/GotaX/go-server-skeleton/pkg/cfg/grpc"
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/http"
 	logrus2 "github.com/GotaX/go-server-skeleton/pkg/cfg/logrus"
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/propagation"
 	"github.com/GotaX/go-server-skeleton/pkg/cfg/tracing"
 	"github.com/GotaX/go-server-skeleton/pkg/ext/config/spring"
 	"github.com/GotaX/go-server-skeleton/pkg/ext/shutdown"
@@ -24,6 +26,7 @@ const (
 var (
 	LogGrpc cfg.ProviderMethod
 	Local   cfg.ProviderMethod
+	Http    cfg.ProviderMethod
 )
 
 func init() {
@@ -32,7 +35,9 @@ func init() {
 	_ = register("log", logrus2.Option, false)
 	LogGrpc = register("logGrpc", logrus2.Option, false)
 	_ = register("trace", tracing.Option, false)
+	_ = register("propagation", propagation.Option, false)
 	Local = register("grpc.local", grpc.Option, true)
+	Http = register("http", http.Option, true)
 
 	logrus.Infof("Init over, profile: %s-%s\n", name, profile)
 }
@@ -1,9 +1,7 @@
 package srvrest
 
 import (
-	"context"
 	"net/http"
-	"time"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
@@ -15,6 +13,7 @@ import (
 	"github.com/GotaX/go-server-skeleton/internal/example/pkg/rpc"
 	"github.com/GotaX/go-server-skeleton/pkg/endpoint/server"
 	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
 )
 
 func Router() http.Handler {
@@ -36,9 +35,8 @@ func HelloHandler(client rpc.HelloServiceClient) func(*gin.Context) {
 	const op errors.Op = "server.HelloHandler"
 
 	return func(c *gin.Context) {
-		ctx, _ := context.WithTimeout(c.Request.Context(), time.Second)
 		req := &rpc.HelloRequest{Greeting: "hero"}
-		resp, err := client.SayHello(ctx, req)
+		resp, err := client.SayHello(c.Request.Context(), req)
 		if err != nil {
 			server.RenderError(c, op, err)
 			return
@@ -63,9 +61,8 @@ func FiberHelloHandler(client rpc.HelloServiceClient) func(*fiber.Ctx) error {
 	const op errors.Op = "server.HelloHandler"
 
 	return func(c *fiber.Ctx) error {
-		ctx, _ := context.WithTimeout(c.Context(), time.Second)
 		req := &rpc.HelloRequest{Greeting: "hero"}
-		resp, err := client.SayHello(ctx, req)
+		resp, err := client.SayHello(tracing.FiberContext(c), req)
 		if err != nil {
 			return errors.E(op, err)
 		}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
-	"net"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -13,24 +12,17 @@ import (
 
 	"github.com/GotaX/go-server-skeleton/internal/example/pkg/cfg"
 	"github.com/GotaX/go-server-skeleton/internal/example/pkg/rpc"
-	"github.com/GotaX/go-server-skeleton/pkg/endpoint"
 	rpc2 "github.com/GotaX/go-server-skeleton/pkg/endpoint/rpc"
 	"github.com/GotaX/go-server-skeleton/pkg/errors"
 )
 
-type mServer struct{ srv *grpc.Server }
-
-func (s mServer) Serve(lis net.Listener) error { return s.srv.Serve(lis) }
-func (s mServer) Stop()                        { s.srv.GracefulStop() }
-
-func Server() endpoint.GrpcServer {
-	srv := rpc2.NewGrpcServer(func(c *rpc2.GrpcConfiguration) {
+func Server() *grpc.Server {
+	return rpc2.NewGrpcServer(func(c *rpc2.GrpcConfiguration) {
 		c.LogEntry = accessLogger()
 		c.LogExtractor = extractor
 
 		c.Register(newService())
 	})
-	return mServer{srv: srv}
 }
 
 func accessLogger() *logrus.Entry {
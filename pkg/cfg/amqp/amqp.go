@@ -2,11 +2,17 @@ package amqp
 
 import (
 	"fmt"
+	"regexp"
+	"sync"
+	"time"
 
+	. "github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	driver "github.com/streadway/amqp"
 
 	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/app"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/health"
 )
 
 var Option = cfg.Option{
@@ -16,21 +22,72 @@ var Option = cfg.Option{
 	OnDestroy: func(v interface{}) { _ = v.(*driver.Connection).Close() },
 }
 
+// ManagedOption provides *Connection, which reconnects by itself.
+// Take channels by Connection.Channel and return them by Connection.Release instead of closing.
+var ManagedOption = cfg.Option{
+	Name:      "AMQP",
+	OnCreate:  newManagedAmqp,
+	OnCreated: registerAmqpStats,
+	OnDestroy: func(v interface{}) { _ = v.(*Connection).Close() },
+}
+
+var (
+	amqpUp = NewGaugeVec(GaugeOpts{
+		Name: "amqp_up",
+		Help: "AMQP 连接状态",
+	}, []string{"name"})
+	amqpReconnects = NewCounterVec(CounterOpts{
+		Name: "amqp_reconnects_total",
+		Help: "AMQP 累计重连次数",
+	}, []string{"name"})
+	amqpPublished = NewCounterVec(CounterOpts{
+		Name: "amqp_published_total",
+		Help: "AMQP 累计发布消息数",
+	}, []string{"name", "exchange"})
+	amqpConfirmed = NewCounterVec(CounterOpts{
+		Name: "amqp_confirmed_total",
+		Help: "AMQP 累计确认消息数",
+	}, []string{"name", "exchange"})
+	amqpNacked = NewCounterVec(CounterOpts{
+		Name: "amqp_nacked_total",
+		Help: "AMQP 累计拒绝消息数",
+	}, []string{"name", "exchange"})
+	amqpReturned = NewCounterVec(CounterOpts{
+		Name: "amqp_returned_total",
+		Help: "AMQP 累计退回消息数",
+	}, []string{"name", "exchange"})
+
+	registerAmqpMetrics = &sync.Once{}
+)
+
+type amqpConfig struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	VHost    string `json:"vhost"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	PoolSize int    `json:"poolSize"`
+}
+
+func (c amqpConfig) url() string {
+	return fmt.Sprintf("amqp://%v:%v@%v:%v/%v",
+		c.Username, c.Password, c.Host, c.Port, c.VHost)
+}
+
 func newAmqp(source cfg.Scanner) (interface{}, error) {
-	var c struct {
-		Host     string `json:"host"`
-		Port     string `json:"port"`
-		VHost    string `json:"vhost"`
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
+	var c amqpConfig
 	if err := source.Scan(&c); err != nil {
 		return nil, err
 	}
+	return driver.Dial(c.url())
+}
 
-	url := fmt.Sprintf("amqp://%v:%v@%v:%v/%v",
-		c.Username, c.Password, c.Host, c.Port, c.VHost)
-	return driver.Dial(url)
+func newManagedAmqp(source cfg.Scanner) (interface{}, error) {
+	var c amqpConfig
+	if err := source.Scan(&c); err != nil {
+		return nil, err
+	}
+	return Dial(c.url(), c.PoolSize)
 }
 
 func logAmqpError(name string, v interface{}) {
@@ -47,3 +104,30 @@ func logAmqpError(name string, v interface{}) {
 		}
 	}()
 }
+
+// registerMetrics is called by Dial too, connections may be created without ManagedOption
+func registerMetrics() {
+	registerAmqpMetrics.Do(func() {
+		MustRegister(amqpUp, amqpReconnects, amqpPublished, amqpConfirmed, amqpNacked, amqpReturned)
+	})
+}
+
+func registerAmqpStats(name string, v interface{}) {
+	registerMetrics()
+
+	conn := v.(*Connection)
+	if m := regexp.MustCompile(`\w+ \(([\w.-]+)\)`).FindStringSubmatch(name); m != nil {
+		name = m[1]
+	}
+	conn.setName("amqp-" + name)
+	health.Register(conn.Name(), conn.Check)
+
+	labels := Labels{"name": conn.Name()}
+	app.RunTicker(fmt.Sprintf("AMQP stats checker %s", name), 5*time.Second, func() {
+		if conn.IsConnected() {
+			amqpUp.With(labels).Set(1)
+		} else {
+			amqpUp.With(labels).Set(0)
+		}
+	})
+}
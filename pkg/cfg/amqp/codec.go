@@ -0,0 +1,64 @@
+package amqp
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/xerrors"
+)
+
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSON  Codec = jsonCodec{}
+	Proto Codec = protoCodec{}
+	// Bytes passes encoded []byte through
+	Bytes Codec = bytesCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                        { return "application/json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type protoCodec struct{}
+
+func (protoCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok {
+		return proto.Marshal(msg)
+	}
+	return nil, xerrors.Errorf("not proto.Message: %T", v)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	if msg, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, msg)
+	}
+	return xerrors.Errorf("not proto.Message: %T", v)
+}
+
+type bytesCodec struct{}
+
+func (bytesCodec) ContentType() string { return "application/octet-stream" }
+
+func (bytesCodec) Marshal(v interface{}) ([]byte, error) {
+	if data, ok := v.([]byte); ok {
+		return data, nil
+	}
+	return nil, xerrors.Errorf("not []byte: %T", v)
+}
+
+func (bytesCodec) Unmarshal(data []byte, v interface{}) error {
+	if p, ok := v.(*[]byte); ok {
+		*p = append((*p)[:0], data...)
+		return nil
+	}
+	return xerrors.Errorf("not *[]byte: %T", v)
+}
@@ -0,0 +1,364 @@
+package amqp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	driver "github.com/streadway/amqp"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+const (
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+var ErrNotConnected = xerrors.New("amqp not connected")
+
+// Channel is a pooled channel bound to a connection generation
+type Channel struct {
+	*driver.Channel
+	gen    uint64
+	closed chan *driver.Error
+}
+
+func (ch *Channel) IsClosed() bool {
+	select {
+	case <-ch.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// amqpConn is implemented by *amqp.Connection of streadway, replaced in tests
+type amqpConn interface {
+	Channel() (*driver.Channel, error)
+	NotifyClose(receiver chan *driver.Error) chan *driver.Error
+	Close() error
+}
+
+func dialDriver(url string) (amqpConn, error) {
+	conn, err := driver.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Connection is a self-healing amqp connection.
+// After connection lost it reconnects with exponential backoff,
+// re-declares registered topology and notifies listeners.
+type Connection struct {
+	url  string
+	dial func(url string) (amqpConn, error)
+	pool chan *Channel
+
+	mu        sync.RWMutex
+	name      string
+	conn      amqpConn
+	gen       uint64
+	topology  []Topology
+	listeners []chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func Dial(url string, poolSize int) (*Connection, error) {
+	return dial(url, poolSize, dialDriver)
+}
+
+func dial(url string, poolSize int, dialer func(url string) (amqpConn, error)) (*Connection, error) {
+	registerMetrics()
+	if poolSize <= 0 {
+		poolSize = 8
+	}
+
+	c := &Connection{
+		url:  url,
+		dial: dialer,
+		name: "amqp",
+		pool: make(chan *Channel, poolSize),
+		done: make(chan struct{}),
+	}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	go c.watch()
+	return c, nil
+}
+
+func (c *Connection) Name() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.name
+}
+
+func (c *Connection) setName(name string) {
+	c.mu.Lock()
+	c.name = name
+	c.mu.Unlock()
+}
+
+// Declare registers topology and declares it immediately if connected
+func (c *Connection) Declare(t Topology) error {
+	c.mu.Lock()
+	c.topology = append(c.topology, t)
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ch.Close() }()
+	return t.declare(ch)
+}
+
+// NotifyReconnect registers a listener, which receives a value after every reconnection
+func (c *Connection) NotifyReconnect(receiver chan struct{}) chan struct{} {
+	c.mu.Lock()
+	c.listeners = append(c.listeners, receiver)
+	c.mu.Unlock()
+	return receiver
+}
+
+// StopNotifyReconnect unregisters a listener of NotifyReconnect
+func (c *Connection) StopNotifyReconnect(receiver chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, listener := range c.listeners {
+		if listener == receiver {
+			c.listeners = append(c.listeners[:i:i], c.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *Connection) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn != nil
+}
+
+func (c *Connection) Check(ctx context.Context) error {
+	if !c.IsConnected() {
+		return errors.E(errors.Unavailable, ErrNotConnected)
+	}
+	return nil
+}
+
+// Channel takes a channel from pool, or opens a new one
+func (c *Connection) Channel() (*Channel, error) {
+	c.mu.RLock()
+	conn, gen := c.conn, c.gen
+	c.mu.RUnlock()
+
+	if conn == nil {
+		return nil, errors.E(errors.Unavailable, ErrNotConnected)
+	}
+
+	for ch := c.take(); ch != nil; ch = c.take() {
+		if ch.gen == gen && !ch.IsClosed() {
+			return ch, nil
+		}
+		_ = ch.Close()
+	}
+
+	raw, err := conn.Channel()
+	if err != nil {
+		return nil, errors.E(errors.Unavailable, err)
+	}
+	return &Channel{
+		Channel: raw,
+		gen:     gen,
+		closed:  raw.NotifyClose(make(chan *driver.Error, 1)),
+	}, nil
+}
+
+// Release returns channel to pool. Channels switched into confirm mode
+// or with custom qos should be closed instead of released.
+func (c *Connection) Release(ch *Channel) {
+	c.mu.RLock()
+	gen := c.gen
+	c.mu.RUnlock()
+
+	if ch.gen != gen || ch.IsClosed() {
+		_ = ch.Close()
+		return
+	}
+
+	select {
+	case c.pool <- ch:
+	default:
+		_ = ch.Close()
+	}
+}
+
+func (c *Connection) Close() (err error) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		c.mu.Lock()
+		conn := c.conn
+		c.conn = nil
+		c.mu.Unlock()
+
+		c.drain()
+		if conn != nil {
+			err = conn.Close()
+		}
+	})
+	return
+}
+
+func (c *Connection) connect() error {
+	conn, err := c.dial(c.url)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	topology := append([]Topology(nil), c.topology...)
+	c.mu.Unlock()
+
+	if err = declareAll(conn, topology); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		// Closed while connecting
+		c.mu.Unlock()
+		return conn.Close()
+	default:
+	}
+	c.conn = conn
+	c.gen++
+	listeners := append([]chan struct{}(nil), c.listeners...)
+	c.mu.Unlock()
+
+	for _, listener := range listeners {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func declareAll(conn amqpConn, topology []Topology) error {
+	if len(topology) == 0 {
+		return nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ch.Close() }()
+
+	for _, t := range topology {
+		if err = t.declare(ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Connection) watch() {
+	for {
+		c.mu.RLock()
+		conn := c.conn
+		c.mu.RUnlock()
+
+		if conn == nil {
+			return
+		}
+
+		closed := conn.NotifyClose(make(chan *driver.Error, 1))
+		select {
+		case <-c.done:
+			return
+		case err := <-closed:
+			select {
+			case <-c.done:
+				return
+			default:
+			}
+
+			entry := logrus.WithField("name", c.Name())
+			if err != nil {
+				entry.WithFields(logrus.Fields{
+					"code":    err.Code,
+					"server":  err.Server,
+					"recover": err.Recover,
+				}).Warn(err.Reason)
+			} else {
+				entry.Warn("Connection closed")
+			}
+
+			c.mu.Lock()
+			if c.conn == conn {
+				c.conn = nil
+			}
+			c.mu.Unlock()
+			c.drain()
+
+			if !c.reconnect() {
+				return
+			}
+		}
+	}
+}
+
+func (c *Connection) reconnect() bool {
+	backoff := minBackoff
+	for {
+		select {
+		case <-c.done:
+			return false
+		case <-time.After(backoff):
+		}
+
+		entry := logrus.WithField("name", c.Name())
+		if err := c.connect(); err != nil {
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			entry.WithError(err).Warnf("Fail to reconnect, retry in %v", backoff)
+			continue
+		}
+
+		amqpReconnects.WithLabelValues(c.Name()).Inc()
+		entry.Info("Reconnected")
+		return true
+	}
+}
+
+func (c *Connection) take() *Channel {
+	select {
+	case ch := <-c.pool:
+		return ch
+	default:
+		return nil
+	}
+}
+
+func (c *Connection) drain() {
+	for ch := c.take(); ch != nil; ch = c.take() {
+		_ = ch.Close()
+	}
+}
@@ -0,0 +1,148 @@
+package amqp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	driver "github.com/streadway/amqp"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+type fakeConn struct {
+	mu     sync.Mutex
+	closes []chan *driver.Error
+}
+
+func (c *fakeConn) Channel() (*driver.Channel, error) {
+	return nil, xerrors.New("no channel of fake connection")
+}
+
+func (c *fakeConn) NotifyClose(receiver chan *driver.Error) chan *driver.Error {
+	c.mu.Lock()
+	c.closes = append(c.closes, receiver)
+	c.mu.Unlock()
+	return receiver
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+// lost simulates closing by server, once watched
+func (c *fakeConn) lost() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.closes) == 0 {
+		c.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		c.mu.Lock()
+	}
+	for _, ch := range c.closes {
+		ch <- &driver.Error{Code: driver.ConnectionForced, Reason: "forced"}
+	}
+	c.closes = nil
+}
+
+type fakeDialer struct {
+	mu    sync.Mutex
+	fail  bool
+	conns []*fakeConn
+}
+
+func (d *fakeDialer) dial(string) (amqpConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.fail {
+		return nil, xerrors.New("refused")
+	}
+	conn := &fakeConn{}
+	d.conns = append(d.conns, conn)
+	return conn, nil
+}
+
+func (d *fakeDialer) last() *fakeConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conns[len(d.conns)-1]
+}
+
+func TestReconnect(t *testing.T) {
+	d := &fakeDialer{}
+	c, err := dial("", 1, d.dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.setName("amqp-test-reconnect")
+	reconnected := c.NotifyReconnect(make(chan struct{}, 1))
+	reconnects := amqpReconnects.WithLabelValues("amqp-test-reconnect")
+	before := testutil.ToFloat64(reconnects)
+
+	if err = c.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	d.mu.Lock()
+	d.fail = true
+	d.mu.Unlock()
+	d.last().lost()
+
+	deadline := time.After(time.Second)
+	for c.IsConnected() {
+		select {
+		case <-deadline:
+			t.Fatal("still connected after lost")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if err = c.Check(context.Background()); errors.Code(err) != errors.Unavailable {
+		t.Fatalf("expected unavailable, got %v", err)
+	}
+	if _, err = c.Channel(); errors.Code(err) != errors.Unavailable {
+		t.Fatalf("expected unavailable channel, got %v", err)
+	}
+
+	d.mu.Lock()
+	d.fail = false
+	d.mu.Unlock()
+	select {
+	case <-reconnected:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for reconnection")
+	}
+	if err = c.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.ToFloat64(reconnects) - before; n != 1 {
+		t.Fatalf("expected 1 reconnection, got %v", n)
+	}
+
+	c.StopNotifyReconnect(reconnected)
+	if len(c.listeners) != 0 {
+		t.Fatalf("expected no listeners, got %d", len(c.listeners))
+	}
+}
+
+func TestChannelPool(t *testing.T) {
+	d := &fakeDialer{}
+	c, err := dial("", 1, d.dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ch := &Channel{gen: c.gen, closed: make(chan *driver.Error)}
+	c.Release(ch)
+	if pooled, err := c.Channel(); err != nil || pooled != ch {
+		t.Fatalf("expected pooled channel, got %v, %v", pooled, err)
+	}
+	// Opens a new one once pool is empty
+	if _, err = c.Channel(); err == nil {
+		t.Fatal("expected error of fake connection")
+	}
+}
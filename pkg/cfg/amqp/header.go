@@ -0,0 +1,30 @@
+package amqp
+
+import (
+	"fmt"
+	"net/http"
+
+	driver "github.com/streadway/amqp"
+)
+
+func HeaderFromTable(table driver.Table) http.Header {
+	header := make(http.Header, len(table))
+	for k, v := range table {
+		switch v := v.(type) {
+		case string:
+			header.Set(k, v)
+		case []byte:
+			header.Set(k, string(v))
+		default:
+			header.Set(k, fmt.Sprint(v))
+		}
+	}
+	return header
+}
+
+// CopyHeader writes header into table, the first value of each key is kept
+func CopyHeader(table driver.Table, header http.Header) {
+	for k := range header {
+		table[k] = header.Get(k)
+	}
+}
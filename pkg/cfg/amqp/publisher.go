@@ -0,0 +1,256 @@
+package amqp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	driver "github.com/streadway/amqp"
+	"go.opencensus.io/trace"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+var (
+	ErrNacked     = xerrors.New("message nacked by broker")
+	ErrReturned   = xerrors.New("message returned as unroutable")
+	ErrNoResponse = xerrors.New("no confirmation from broker")
+)
+
+type Message struct {
+	Exchange  string
+	Key       string
+	Mandatory bool
+	// Transient messages are not persisted by broker
+	Transient bool
+
+	MessageId     string
+	CorrelationId string
+	Type          string
+	// Override content type of publisher codec
+	ContentType string
+	Headers     driver.Table
+	Expiration  time.Duration
+
+	// Encoded by publisher codec
+	Body interface{}
+}
+
+// publishChannel is implemented by *Channel, replaced in tests
+type publishChannel interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg driver.Publishing) error
+	Close() error
+	IsClosed() bool
+}
+
+type confirmChannel struct {
+	publishChannel
+	gen      uint64
+	confirms chan driver.Confirmation
+	returns  chan driver.Return
+}
+
+// Publisher publishes messages in confirm mode, one in-flight message per channel
+type Publisher struct {
+	conn    *Connection
+	codec   Codec
+	timeout time.Duration
+	pool    chan *confirmChannel
+	open    func() (*confirmChannel, error)
+}
+
+func (c *Connection) Publisher(codec Codec, timeout time.Duration) *Publisher {
+	if codec == nil {
+		codec = JSON
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	p := &Publisher{
+		conn:    c,
+		codec:   codec,
+		timeout: timeout,
+		pool:    make(chan *confirmChannel, cap(c.pool)),
+	}
+	p.open = p.openConfirm
+	return p
+}
+
+func (p *Publisher) Publish(ctx context.Context, msg Message) (err error) {
+	const op errors.Op = "amqp.Publish"
+
+	requestId := tracing.RequestId(ctx)
+	ctx, span := trace.StartSpan(ctx, "amqp.publish:"+msg.Exchange+"/"+msg.Key,
+		trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.SetStatus(trace.Status{Code: int32(errors.Code(err)), Message: err.Error()})
+		}
+		span.End()
+	}()
+
+	body, err := p.codec.Marshal(msg.Body)
+	if err != nil {
+		return errors.E(op, errors.InvalidArgument, err)
+	}
+
+	publishing := driver.Publishing{
+		Headers:       headers(span, requestId, msg.Headers),
+		ContentType:   p.codec.ContentType(),
+		DeliveryMode:  driver.Persistent,
+		MessageId:     msg.MessageId,
+		CorrelationId: msg.CorrelationId,
+		Type:          msg.Type,
+		Timestamp:     time.Now(),
+		Body:          body,
+	}
+	if msg.ContentType != "" {
+		publishing.ContentType = msg.ContentType
+	}
+	if msg.Transient {
+		publishing.DeliveryMode = driver.Transient
+	}
+	if msg.Expiration > 0 {
+		publishing.Expiration = strconv.FormatInt(int64(msg.Expiration/time.Millisecond), 10)
+	}
+
+	if err = p.publish(ctx, msg.Exchange, msg.Key, msg.Mandatory, publishing); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// PublishRaw publishes an encoded message in confirm mode, e.g. forwarding a delivery as is
+func (p *Publisher) PublishRaw(ctx context.Context, exchange, key string, mandatory bool, msg driver.Publishing) (err error) {
+	const op errors.Op = "amqp.PublishRaw"
+
+	ctx, span := trace.StartSpan(ctx, "amqp.publish:"+exchange+"/"+key,
+		trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.SetStatus(trace.Status{Code: int32(errors.Code(err)), Message: err.Error()})
+		}
+		span.End()
+	}()
+
+	if err = p.publish(ctx, exchange, key, mandatory, msg); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+func (p *Publisher) publish(ctx context.Context, exchange, key string, mandatory bool, publishing driver.Publishing) error {
+	const op errors.Op = "amqp.publish"
+
+	ch, err := p.channel()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	labels := []string{p.conn.Name(), exchange}
+	if err = ch.Publish(exchange, key, mandatory, false, publishing); err != nil {
+		_ = ch.Close()
+		return errors.E(op, errors.Unavailable, err)
+	}
+	amqpPublished.WithLabelValues(labels...).Inc()
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	select {
+	case confirm, ok := <-ch.confirms:
+		if !ok {
+			return errors.E(op, errors.Unavailable, ErrNoResponse)
+		}
+		if !confirm.Ack {
+			amqpNacked.WithLabelValues(labels...).Inc()
+			p.release(ch)
+			return errors.E(op, errors.Unavailable, ErrNacked)
+		}
+	case <-timer.C:
+		// Confirmation may arrive later, channel is not reusable
+		_ = ch.Close()
+		return errors.E(op, errors.DeadlineExceeded, ErrNoResponse)
+	case <-ctx.Done():
+		_ = ch.Close()
+		return errors.E(op, ctx.Err())
+	}
+
+	// Return always arrives before ack of the same message
+	select {
+	case ret := <-ch.returns:
+		amqpReturned.WithLabelValues(labels...).Inc()
+		p.release(ch)
+		return errors.E(op, errors.NotFound, xerrors.Errorf("%w: %d %s", ErrReturned, ret.ReplyCode, ret.ReplyText))
+	default:
+	}
+
+	amqpConfirmed.WithLabelValues(labels...).Inc()
+	p.release(ch)
+	return nil
+}
+
+func headers(span *trace.Span, requestId string, extra driver.Table) driver.Table {
+	headers := driver.Table{}
+	for k, v := range extra {
+		headers[k] = v
+	}
+
+	header := http.Header{}
+	tracing.SpanContextToHeader(span.SpanContext(), header)
+	header.Set(tracing.HeaderRequestId, requestId)
+	CopyHeader(headers, header)
+	return headers
+}
+
+func (p *Publisher) channel() (*confirmChannel, error) {
+	p.conn.mu.RLock()
+	gen := p.conn.gen
+	p.conn.mu.RUnlock()
+
+	for ch := p.take(); ch != nil; ch = p.take() {
+		if ch.gen == gen && !ch.IsClosed() {
+			return ch, nil
+		}
+		_ = ch.Close()
+	}
+
+	return p.open()
+}
+
+func (p *Publisher) openConfirm() (*confirmChannel, error) {
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err = ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, errors.E(errors.Unavailable, err)
+	}
+	return &confirmChannel{
+		publishChannel: ch,
+		gen:            ch.gen,
+		confirms:       ch.NotifyPublish(make(chan driver.Confirmation, 1)),
+		returns:        ch.NotifyReturn(make(chan driver.Return, 1)),
+	}, nil
+}
+
+func (p *Publisher) take() *confirmChannel {
+	select {
+	case ch := <-p.pool:
+		return ch
+	default:
+		return nil
+	}
+}
+
+func (p *Publisher) release(ch *confirmChannel) {
+	select {
+	case p.pool <- ch:
+	default:
+		_ = ch.Close()
+	}
+}
@@ -0,0 +1,138 @@
+package amqp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	driver "github.com/streadway/amqp"
+	"google.golang.org/grpc/codes"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+type fakeChannel struct {
+	reply     string
+	confirms  chan driver.Confirmation
+	returns   chan driver.Return
+	published []driver.Publishing
+	closed    bool
+}
+
+func (ch *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg driver.Publishing) error {
+	ch.published = append(ch.published, msg)
+	tag := uint64(len(ch.published))
+	switch ch.reply {
+	case "ack":
+		ch.confirms <- driver.Confirmation{DeliveryTag: tag, Ack: true}
+	case "nack":
+		ch.confirms <- driver.Confirmation{DeliveryTag: tag}
+	case "return":
+		ch.returns <- driver.Return{ReplyCode: driver.NoRoute, ReplyText: "NO_ROUTE"}
+		ch.confirms <- driver.Confirmation{DeliveryTag: tag, Ack: true}
+	}
+	return nil
+}
+
+func (ch *fakeChannel) Close() error {
+	ch.closed = true
+	return nil
+}
+
+func (ch *fakeChannel) IsClosed() bool {
+	return ch.closed
+}
+
+func TestPublish(t *testing.T) {
+	d := &fakeDialer{}
+	c, err := dial("", 1, d.dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	testCases := []struct {
+		reply  string
+		code   codes.Code
+		reused bool
+	}{
+		{"ack", errors.OK, true},
+		{"nack", errors.Unavailable, true},
+		{"return", errors.NotFound, true},
+		{"none", errors.DeadlineExceeded, false},
+	}
+
+	for _, tc := range testCases {
+		var opened []*fakeChannel
+		p := c.Publisher(nil, 50*time.Millisecond)
+		p.open = func() (*confirmChannel, error) {
+			ch := &fakeChannel{
+				reply:    tc.reply,
+				confirms: make(chan driver.Confirmation, 1),
+				returns:  make(chan driver.Return, 1),
+			}
+			opened = append(opened, ch)
+			return &confirmChannel{publishChannel: ch, gen: c.gen, confirms: ch.confirms, returns: ch.returns}, nil
+		}
+
+		ctx := tracing.WithRequestId(context.Background(), "r1")
+		for i := 0; i < 2; i++ {
+			err = p.Publish(ctx, Message{Exchange: "ex", Key: "k", Body: map[string]int{"id": 1}})
+			if code := errors.Code(err); err != nil && code != tc.code || err == nil && tc.code != errors.OK {
+				t.Fatalf("%s: expected %v got %v", tc.reply, tc.code, err)
+			}
+		}
+
+		// Channels are pooled unless confirmation is lost
+		if reused := len(opened) == 1; reused != tc.reused || opened[0].closed == tc.reused {
+			t.Fatalf("%s: expected reused %v, opened %d", tc.reply, tc.reused, len(opened))
+		}
+		msg := opened[0].published[0]
+		if msg.ContentType != JSON.ContentType() || string(msg.Body) != `{"id":1}` || msg.DeliveryMode != driver.Persistent {
+			t.Fatalf("%s: unexpected publishing %+v", tc.reply, msg)
+		}
+		if msg.Headers[tracing.HeaderRequestId] != "r1" {
+			t.Fatalf("%s: expected request id in headers, got %v", tc.reply, msg.Headers)
+		}
+	}
+}
+
+func TestCodec(t *testing.T) {
+	var (
+		m     map[string]string
+		msg   wrappers.StringValue
+		bytes []byte
+	)
+	testCases := []struct {
+		codec Codec
+		in    interface{}
+		out   interface{}
+		check func() bool
+	}{
+		{JSON, map[string]string{"a": "b"}, &m, func() bool { return m["a"] == "b" }},
+		{Proto, &wrappers.StringValue{Value: "v"}, &msg, func() bool { return msg.Value == "v" }},
+		{Bytes, []byte("raw"), &bytes, func() bool { return string(bytes) == "raw" }},
+	}
+
+	for _, c := range testCases {
+		data, err := c.codec.Marshal(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.codec.Unmarshal(data, c.out); err != nil {
+			t.Fatal(err)
+		}
+		if !c.check() {
+			t.Fatalf("%s: unexpected %v", c.codec.ContentType(), c.out)
+		}
+	}
+
+	if _, err := Proto.Marshal("text"); err == nil {
+		t.Fatal("expected error of non proto message")
+	}
+	if _, err := Bytes.Marshal("text"); err == nil {
+		t.Fatal("expected error of non bytes")
+	}
+}
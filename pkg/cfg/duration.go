@@ -0,0 +1,43 @@
+package cfg
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+var durationType = reflect.TypeOf(Duration(0))
+
+// Duration accepts "1.5s" style string or nanoseconds in config
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case float64:
+		*d = Duration(v)
+	case string:
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(dur)
+	case nil:
+		*d = 0
+	default:
+		return &json.UnmarshalTypeError{Value: string(data), Type: durationType}
+	}
+	return nil
+}
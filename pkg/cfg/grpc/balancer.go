@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+const (
+	RoundRobin   = "round_robin"
+	LeastRequest = "least_request"
+)
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(LeastRequest, leastRequestBuilder{}, base.Config{HealthCheck: true}))
+}
+
+type leastRequestBuilder struct{}
+
+func (leastRequestBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &leastRequestPicker{}
+	for sc := range info.ReadySCs {
+		p.items = append(p.items, &pickItem{sc: sc})
+	}
+	return p
+}
+
+type pickItem struct {
+	sc       balancer.SubConn
+	inflight int64
+}
+
+// leastRequestPicker picks the less loaded one of two random sub connections (power of two choices)
+type leastRequestPicker struct {
+	items []*pickItem
+}
+
+func (p *leastRequestPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	item := p.items[0]
+	if n := len(p.items); n > 1 {
+		i := rand.Intn(n)
+		a, b := p.items[i], p.items[(i+1+rand.Intn(n-1))%n]
+		if item = a; atomic.LoadInt64(&b.inflight) < atomic.LoadInt64(&a.inflight) {
+			item = b
+		}
+	}
+
+	atomic.AddInt64(&item.inflight, 1)
+	return balancer.PickResult{
+		SubConn: item.sc,
+		Done:    func(balancer.DoneInfo) { atomic.AddInt64(&item.inflight, -1) },
+	}, nil
+}
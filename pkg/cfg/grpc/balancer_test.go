@@ -0,0 +1,30 @@
+package grpc
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/balancer"
+)
+
+type fakeSubConn struct {
+	balancer.SubConn
+	name string
+}
+
+func TestLeastRequestPicker(t *testing.T) {
+	a, b := &fakeSubConn{name: "a"}, &fakeSubConn{name: "b"}
+	p := &leastRequestPicker{items: []*pickItem{{sc: a}, {sc: b}}}
+
+	// Both are compared with two items, so unfinished calls spread evenly
+	first, _ := p.Pick(balancer.PickInfo{})
+	second, _ := p.Pick(balancer.PickInfo{})
+	if first.SubConn == second.SubConn {
+		t.Fatal("expected the less loaded sub connection")
+	}
+
+	second.Done(balancer.DoneInfo{})
+	third, _ := p.Pick(balancer.PickInfo{})
+	if third.SubConn != second.SubConn {
+		t.Fatalf("expected %v got %v", second.SubConn, third.SubConn)
+	}
+}
@@ -0,0 +1,239 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"golang.org/x/xerrors"
+	driver "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/breaker"
+)
+
+type TLSConfig struct {
+	Enable     bool   `json:"enable"`
+	CA         string `json:"ca"`
+	Cert       string `json:"cert"`
+	Key        string `json:"key"`
+	ServerName string `json:"serverName"`
+	Insecure   bool   `json:"insecure"`
+}
+
+type RetryConfig struct {
+	Max     uint         `json:"max"`
+	Codes   []string     `json:"codes"`
+	Backoff cfg.Duration `json:"backoff"`
+}
+
+type KeepaliveConfig struct {
+	Time                cfg.Duration `json:"time"`
+	Timeout             cfg.Duration `json:"timeout"`
+	PermitWithoutStream *bool        `json:"permitWithoutStream"`
+}
+
+type BreakerConfig struct {
+	Enable           bool         `json:"enable"`
+	Window           cfg.Duration `json:"window"`
+	MinRequests      int          `json:"minRequests"`
+	FailureRatio     float64      `json:"failureRatio"`
+	CoolDown         cfg.Duration `json:"coolDown"`
+	HalfOpenRequests int          `json:"halfOpenRequests"`
+}
+
+// Config of grpc client, also accepts a bare target string
+type Config struct {
+	// E.g. "dns:///host:port", "static:///host1:port,host2:port", see resolver.go for more
+	Target string `json:"target"`
+	// Shortcut of static target, used if target is empty
+	Endpoints Endpoints `json:"endpoints"`
+
+	Authority string          `json:"authority"`
+	TLS       TLSConfig       `json:"tls"`
+	Retry     RetryConfig     `json:"retry"`
+	Keepalive KeepaliveConfig `json:"keepalive"`
+	Breaker   BreakerConfig   `json:"breaker"`
+
+	// Deadline of unary calls without one, negative to disable.
+	// Note: 0 (unset) means the default 10s, it meant no deadline before client metrics were added.
+	Timeout cfg.Duration `json:"timeout"`
+
+	MaxRecvMsgSize int `json:"maxRecvMsgSize"`
+	MaxSendMsgSize int `json:"maxSendMsgSize"`
+
+	// Only "gzip" supported
+	Compression string `json:"compression"`
+	// E.g. "round_robin", "least_request", "pick_first",
+	// default "round_robin" for discovery targets
+	LoadBalancing string `json:"loadBalancing"`
+}
+
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var target string
+	if err := json.Unmarshal(data, &target); err == nil {
+		*c = Config{Target: target}
+		return nil
+	}
+
+	type plain Config
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+func (c Config) target() string {
+	if c.Target == "" && len(c.Endpoints) > 0 {
+		return SchemeStatic + ":///" + strings.Join(c.Endpoints, ",")
+	}
+	return c.Target
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return 10 * time.Second
+	}
+	return c.Timeout.Std()
+}
+
+func (c Config) retryOptions() ([]retry.CallOption, error) {
+	backoff := 100 * time.Millisecond
+	if c.Retry.Backoff > 0 {
+		backoff = c.Retry.Backoff.Std()
+	}
+
+	retryCodes := []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.Aborted}
+	if len(c.Retry.Codes) > 0 {
+		retryCodes = retryCodes[:0]
+		for _, str := range c.Retry.Codes {
+			code := errors.StrToCode(str)
+			if code == errors.OK {
+				return nil, xerrors.Errorf("invalid retry code: %q", str)
+			}
+			retryCodes = append(retryCodes, code)
+		}
+	}
+
+	return []retry.CallOption{
+		retry.WithMax(c.Retry.Max),
+		retry.WithBackoff(retry.BackoffExponential(backoff)),
+		retry.WithCodes(retryCodes...),
+	}, nil
+}
+
+func (c Config) breakerOptions() breaker.Options {
+	return breaker.Options{
+		Window:           c.Breaker.Window.Std(),
+		MinRequests:      c.Breaker.MinRequests,
+		FailureRatio:     c.Breaker.FailureRatio,
+		CoolDown:         c.Breaker.CoolDown.Std(),
+		HalfOpenRequests: c.Breaker.HalfOpenRequests,
+	}
+}
+
+func (c Config) keepaliveParams() keepalive.ClientParameters {
+	// Ref: https://github.com/grpc/grpc-go/blob/master/examples/features/keepalive/client/main.go
+	kacp := keepalive.ClientParameters{
+		Time:                10 * time.Second, // send pings every 10 seconds if there is no activity
+		Timeout:             3 * time.Second,  // wait 3 seconds for ping ack before considering the connection dead
+		PermitWithoutStream: true,             // send pings even without active streams
+	}
+	if c.Keepalive.Time > 0 {
+		kacp.Time = c.Keepalive.Time.Std()
+	}
+	if c.Keepalive.Timeout > 0 {
+		kacp.Timeout = c.Keepalive.Timeout.Std()
+	}
+	if c.Keepalive.PermitWithoutStream != nil {
+		kacp.PermitWithoutStream = *c.Keepalive.PermitWithoutStream
+	}
+	return kacp
+}
+
+func (c Config) transportOption() (driver.DialOption, error) {
+	if !c.TLS.Enable {
+		return driver.WithInsecure(), nil
+	}
+
+	tc := &tls.Config{
+		ServerName:         c.TLS.ServerName,
+		InsecureSkipVerify: c.TLS.Insecure,
+	}
+	if c.TLS.CA != "" {
+		data, err := ioutil.ReadFile(c.TLS.CA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, xerrors.Errorf("no certificate found in %s", c.TLS.CA)
+		}
+		tc.RootCAs = pool
+	}
+	if c.TLS.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLS.Cert, c.TLS.Key)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return driver.WithTransportCredentials(credentials.NewTLS(tc)), nil
+}
+
+func (c Config) dialOptions() ([]driver.DialOption, error) {
+	transport, err := c.transportOption()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		opts     = []driver.DialOption{transport, driver.WithKeepaliveParams(c.keepaliveParams())}
+		callOpts []driver.CallOption
+	)
+
+	if c.Authority != "" {
+		opts = append(opts, driver.WithAuthority(c.Authority))
+	}
+
+	lb := c.LoadBalancing
+	if target := c.target(); isDiscovery(target) {
+		rb, err := NewResolver(target)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, driver.WithResolvers(rb))
+		if lb == "" {
+			lb = RoundRobin
+		}
+	}
+	if lb != "" {
+		opts = append(opts, driver.WithDefaultServiceConfig(
+			fmt.Sprintf(`{"loadBalancingConfig": [{%q: {}}]}`, lb)))
+	}
+
+	if c.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, driver.MaxCallRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, driver.MaxCallSendMsgSize(c.MaxSendMsgSize))
+	}
+	switch c.Compression {
+	case "":
+	case gzip.Name:
+		callOpts = append(callOpts, driver.UseCompressor(gzip.Name))
+	default:
+		return nil, xerrors.Errorf("unsupported compression: %q", c.Compression)
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, driver.WithDefaultCallOptions(callOpts...))
+	}
+	return opts, nil
+}
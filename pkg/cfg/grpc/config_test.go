@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+)
+
+func TestConfigUnmarshal(t *testing.T) {
+	testCases := []struct {
+		data    string
+		target  string
+		timeout time.Duration
+	}{
+		{`"localhost:8082"`, "localhost:8082", 0},
+		{`{"target": "dns:///app:8082", "timeout": "1.5s"}`, "dns:///app:8082", 1500 * time.Millisecond},
+		{`{"target": "app:8082", "timeout": 1000000}`, "app:8082", time.Millisecond},
+	}
+
+	for _, c := range testCases {
+		var config Config
+		if err := json.Unmarshal([]byte(c.data), &config); err != nil {
+			t.Fatalf("failed to unmarshal %s: %v", c.data, err)
+		}
+		if config.Target != c.target || config.Timeout.Std() != c.timeout {
+			t.Fatalf("failed to unmarshal %s: expected %s/%v got %s/%v",
+				c.data, c.target, c.timeout, config.Target, config.Timeout.Std())
+		}
+	}
+}
+
+func TestRetryOptions(t *testing.T) {
+	if _, err := (Config{Retry: RetryConfig{Codes: []string{"UNAVAILABLE"}}}).retryOptions(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Config{Retry: RetryConfig{Codes: []string{"BROKEN"}}}).retryOptions(); err == nil {
+		t.Fatal("expected error for invalid code")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	testCases := []struct {
+		timeout  time.Duration
+		expected time.Duration
+	}{
+		{0, 10 * time.Second},
+		{time.Second, time.Second},
+		{-1, -1},
+	}
+	for _, c := range testCases {
+		if actual := (Config{Timeout: cfg.Duration(c.timeout)}).timeout(); actual != c.expected {
+			t.Fatalf("%v: expected %v got %v", c.timeout, c.expected, actual)
+		}
+	}
+}
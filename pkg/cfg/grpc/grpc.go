@@ -1,18 +1,15 @@
 package grpc
 
 import (
-	"time"
-
-	"google.golang.org/grpc/keepalive"
-
 	retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
 	"go.opencensus.io/plugin/ocgrpc"
 	"go.opencensus.io/trace"
 	driver "google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 
 	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/breaker"
 	"github.com/GotaX/go-server-skeleton/pkg/ext/grpc"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
 )
 
 var Option = cfg.Option{
@@ -21,36 +18,50 @@ var Option = cfg.Option{
 }
 
 func newGrpc(source cfg.Scanner) (interface{}, error) {
-	var target string
-	if err := source.Scan(&target); err != nil {
+	var c Config
+	if err := source.Scan(&c); err != nil {
+		return nil, err
+	}
+	return Dial(c)
+}
+
+func Dial(c Config) (*driver.ClientConn, error) {
+	opts, err := c.retryOptions()
+	if err != nil {
 		return nil, err
 	}
 
-	opts := []retry.CallOption{
-		retry.WithBackoff(retry.BackoffExponential(100 * time.Millisecond)),
-		retry.WithCodes(codes.Unavailable, codes.ResourceExhausted, codes.Aborted),
+	dialOpts, err := c.dialOptions()
+	if err != nil {
+		return nil, err
 	}
 
-	// Ref: https://github.com/grpc/grpc-go/blob/master/examples/features/keepalive/client/main.go
-	kacp := keepalive.ClientParameters{
-		Time:                10 * time.Second, // send pings every 10 seconds if there is no activity
-		Timeout:             3 * time.Second,  // wait 3 seconds for ping ack before considering the connection dead
-		PermitWithoutStream: true,             // send pings even without active streams
+	streamInterceptors := []driver.StreamClientInterceptor{
+		grpc.StreamClientMetrics(),
+		propagation.StreamClientInterceptor(),
+		retry.StreamClientInterceptor(opts...),
+	}
+	unaryInterceptors := []driver.UnaryClientInterceptor{
+		grpc.UnaryClientMetrics(),
+		propagation.UnaryClientInterceptor(),
+		grpc.UnaryClientTimeout(c.timeout()),
+		retry.UnaryClientInterceptor(opts...),
+	}
+	// Inside retry, so attempts fail fast while open
+	if c.Breaker.Enable {
+		group := breaker.NewGroup(c.breakerOptions())
+		streamInterceptors = append(streamInterceptors, breaker.StreamClientInterceptor(group))
+		unaryInterceptors = append(unaryInterceptors, breaker.UnaryClientInterceptor(group))
 	}
 
-	return driver.Dial(target, driver.WithInsecure(),
+	dialOpts = append(dialOpts,
 		driver.WithStatsHandler(&ocgrpc.ClientHandler{
 			StartOptions: trace.StartOptions{
 				Sampler: trace.AlwaysSample(),
 			},
 		}),
-		driver.WithChainStreamInterceptor(
-			retry.StreamClientInterceptor(opts...),
-			grpc.StreamClientErrorHandler(),
-		),
-		driver.WithChainUnaryInterceptor(
-			retry.UnaryClientInterceptor(opts...),
-			grpc.UnaryClientErrorHandler(),
-		),
-		driver.WithKeepaliveParams(kacp))
+		driver.WithChainStreamInterceptor(append(streamInterceptors, grpc.StreamClientErrorHandler())...),
+		driver.WithChainUnaryInterceptor(append(unaryInterceptors, grpc.UnaryClientErrorHandler())...))
+
+	return driver.Dial(c.target(), dialOpts...)
 }
@@ -0,0 +1,333 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc/resolver"
+	"gopkg.in/yaml.v2"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+)
+
+// Supported discovery targets:
+//
+//	static:///10.0.0.1:9090,10.0.0.2:9090
+//	srv:///_grpc._tcp.backend.svc.cluster.local
+//	file:///etc/app/backend.yaml (absolute) or file://conf/backend.json (relative)
+//	config:///backend (fed by UpdateEndpoints or WatchEndpoints)
+const (
+	SchemeStatic = "static"
+	SchemeSRV    = "srv"
+	SchemeFile   = "file"
+	SchemeConfig = "config"
+)
+
+var (
+	ErrNoEndpoints = xerrors.New("no endpoints")
+
+	// Replaceable for offline tests
+	lookupSRV = net.DefaultResolver.LookupSRV
+
+	srvInterval  = 30 * time.Second
+	fileInterval = time.Second
+
+	endpoints = &registry{
+		values:    make(map[string][]string),
+		listeners: make(map[string]map[chan struct{}]struct{}),
+	}
+)
+
+// Endpoints accepts a list or a comma separated string
+type Endpoints []string
+
+func (e *Endpoints) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*e = splitEndpoints(str)
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(e))
+}
+
+// UpdateEndpoints replaces endpoints of target "config:///<name>"
+func UpdateEndpoints(name string, addrs ...string) {
+	endpoints.set(name, addrs)
+}
+
+// WatchEndpoints feeds endpoints of name from config values, returns when next fails
+func WatchEndpoints(name string, next func() (cfg.Scanner, error)) error {
+	entry := logrus.WithField("name", name)
+	for {
+		source, err := next()
+		if err != nil {
+			return err
+		}
+		var addrs Endpoints
+		if err = source.Scan(&addrs); err != nil {
+			entry.WithError(err).Warn("Fail to scan endpoints")
+			continue
+		}
+		UpdateEndpoints(name, addrs...)
+	}
+}
+
+// NewResolver creates resolver builder of target, use it with grpc.WithResolvers
+func NewResolver(target string) (resolver.Builder, error) {
+	scheme, authority, endpoint, ok := splitTarget(target)
+	if !ok {
+		return nil, xerrors.Errorf("invalid target: %q", target)
+	}
+
+	b := &builder{scheme: scheme, target: target}
+	switch scheme {
+	case SchemeStatic:
+		addrs := splitEndpoints(endpoint)
+		b.lookup = func(context.Context) ([]string, error) { return addrs, nil }
+	case SchemeSRV:
+		b.lookup = func(ctx context.Context) ([]string, error) { return lookupSRVAddrs(ctx, endpoint) }
+		b.interval = srvInterval
+	case SchemeFile:
+		// Empty authority means absolute path
+		path := authority + "/" + endpoint
+		b.lookup = func(context.Context) ([]string, error) { return readEndpoints(path) }
+		b.interval = fileInterval
+	case SchemeConfig:
+		b.lookup = func(context.Context) ([]string, error) { return endpoints.get(endpoint), nil }
+		b.notify = func() (<-chan struct{}, func()) { return endpoints.watch(endpoint) }
+	default:
+		return nil, xerrors.Errorf("unsupported scheme: %q", scheme)
+	}
+	return b, nil
+}
+
+func isDiscovery(target string) bool {
+	scheme, _, _, _ := splitTarget(target)
+	switch scheme {
+	case SchemeStatic, SchemeSRV, SchemeFile, SchemeConfig:
+		return true
+	default:
+		return false
+	}
+}
+
+// splitTarget parses "scheme://authority/endpoint"
+func splitTarget(target string) (scheme, authority, endpoint string, ok bool) {
+	i := strings.Index(target, "://")
+	if i <= 0 {
+		return "", "", "", false
+	}
+	scheme, rest := target[:i], target[i+3:]
+	if j := strings.Index(rest, "/"); j >= 0 {
+		return scheme, rest[:j], rest[j+1:], true
+	}
+	return scheme, "", rest, true
+}
+
+func splitEndpoints(str string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(str, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func lookupSRVAddrs(ctx context.Context, name string) ([]string, error) {
+	_, records, err := lookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(records))
+	for _, r := range records {
+		host := strings.TrimSuffix(r.Target, ".")
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(r.Port))))
+	}
+	return addrs, nil
+}
+
+// readEndpoints reads file of a list or {endpoints: [...]}, YAML is a superset of JSON
+func readEndpoints(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEndpoints(data)
+}
+
+func decodeEndpoints(data []byte) ([]string, error) {
+	var list []string
+	if err := yaml.Unmarshal(data, &list); err == nil {
+		return list, nil
+	}
+	var doc struct {
+		Endpoints []string `yaml:"endpoints"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Endpoints, nil
+}
+
+type builder struct {
+	scheme   string
+	target   string
+	lookup   func(ctx context.Context) ([]string, error)
+	interval time.Duration
+	notify   func() (<-chan struct{}, func())
+}
+
+func (b *builder) Scheme() string {
+	return b.scheme
+}
+
+func (b *builder) Build(_ resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &discovery{
+		cc:     cc,
+		cancel: cancel,
+		now:    make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		entry:  logrus.WithField("target", b.target),
+	}
+
+	var changed <-chan struct{}
+	if b.notify != nil {
+		ch, unwatch := b.notify()
+		changed, r.cancel = ch, func() { unwatch(); cancel() }
+	}
+	go r.watch(ctx, b.lookup, b.interval, changed)
+	return r, nil
+}
+
+type discovery struct {
+	cc     resolver.ClientConn
+	cancel func()
+	now    chan struct{}
+	done   chan struct{}
+	entry  *logrus.Entry
+}
+
+func (r *discovery) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+func (r *discovery) Close() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *discovery) watch(ctx context.Context, lookup func(ctx context.Context) ([]string, error),
+	interval time.Duration, changed <-chan struct{}) {
+	defer close(r.done)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var last []string
+	for {
+		addrs, err := lookup(ctx)
+		if err == nil && len(addrs) == 0 {
+			err = ErrNoEndpoints
+		}
+		if err != nil {
+			r.entry.WithError(err).Warn("Fail to resolve")
+			r.cc.ReportError(err)
+		} else if addrs = sortedCopy(addrs); !equal(addrs, last) {
+			last = addrs
+			r.entry.Debugf("Resolved: %v", addrs)
+
+			state := resolver.State{}
+			for _, addr := range addrs {
+				state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+			}
+			if err = r.cc.UpdateState(state); err != nil {
+				r.entry.WithError(err).Debug("Fail to update state")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-r.now:
+		case <-changed:
+		}
+	}
+}
+
+func sortedCopy(addrs []string) []string {
+	result := append([]string(nil), addrs...)
+	sort.Strings(result)
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type registry struct {
+	mu        sync.Mutex
+	values    map[string][]string
+	listeners map[string]map[chan struct{}]struct{}
+}
+
+func (r *registry) get(name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.values[name]
+}
+
+func (r *registry) set(name string, addrs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[name] = append([]string(nil), addrs...)
+	for ch := range r.listeners[name] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *registry) watch(name string) (<-chan struct{}, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if r.listeners[name] == nil {
+		r.listeners[name] = make(map[chan struct{}]struct{})
+	}
+	r.listeners[name][ch] = struct{}{}
+
+	return ch, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.listeners[name], ch)
+	}
+}
@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+type fakeClientConn struct {
+	resolver.ClientConn
+	states chan []string
+}
+
+func (f *fakeClientConn) UpdateState(state resolver.State) error {
+	var addrs []string
+	for _, a := range state.Addresses {
+		addrs = append(addrs, a.Addr)
+	}
+	f.states <- addrs
+	return nil
+}
+
+func (f *fakeClientConn) ReportError(error) {}
+
+func (f *fakeClientConn) next(t *testing.T) []string {
+	select {
+	case addrs := <-f.states:
+		return addrs
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for state")
+		return nil
+	}
+}
+
+func TestDecodeEndpoints(t *testing.T) {
+	testCases := []struct {
+		data     string
+		expected []string
+	}{
+		{`["a:1", "b:2"]`, []string{"a:1", "b:2"}},
+		{`{"endpoints": ["a:1"]}`, []string{"a:1"}},
+		{"- a:1\n- b:2\n", []string{"a:1", "b:2"}},
+		{"endpoints:\n  - a:1\n", []string{"a:1"}},
+	}
+
+	for _, c := range testCases {
+		addrs, err := decodeEndpoints([]byte(c.data))
+		if err != nil {
+			t.Fatalf("failed to decode %q: %v", c.data, err)
+		}
+		if !reflect.DeepEqual(addrs, c.expected) {
+			t.Fatalf("failed to decode %q: expected %v got %v", c.data, c.expected, addrs)
+		}
+	}
+}
+
+func TestStaticResolver(t *testing.T) {
+	b, err := NewResolver("static:///b:2, a:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := &fakeClientConn{states: make(chan []string, 1)}
+	r, err := b.Build(resolver.Target{}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if addrs := cc.next(t); !reflect.DeepEqual(addrs, []string{"a:1", "b:2"}) {
+		t.Fatalf("unexpected addresses: %v", addrs)
+	}
+}
+
+func TestConfigResolver(t *testing.T) {
+	b, err := NewResolver("config:///test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := &fakeClientConn{states: make(chan []string, 1)}
+	r, err := b.Build(resolver.Target{}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	UpdateEndpoints("test", "a:1")
+	if addrs := cc.next(t); !reflect.DeepEqual(addrs, []string{"a:1"}) {
+		t.Fatalf("unexpected addresses: %v", addrs)
+	}
+	UpdateEndpoints("test", "a:1", "b:2")
+	if addrs := cc.next(t); !reflect.DeepEqual(addrs, []string{"a:1", "b:2"}) {
+		t.Fatalf("unexpected addresses: %v", addrs)
+	}
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint"
+)
+
+// Option loads endpoint.HttpConfig, pass it by endpoint.HttpWithConfig,
+// e.g. {"readTimeout": "30s", "maxConnections": 1000, "tls": {"cert": "...", "key": "..."}}
+var Option = cfg.Option{
+	Name:     "Http",
+	OnCreate: newHttp,
+}
+
+func newHttp(source cfg.Scanner) (interface{}, error) {
+	var c endpoint.HttpConfig
+	if err := source.Scan(&c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
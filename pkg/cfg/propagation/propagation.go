@@ -0,0 +1,22 @@
+package propagation
+
+import (
+	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
+)
+
+// Option configures keys to propagate, register it before serving,
+// e.g. {"keys": ["x-request-id"], "restricted": {"authorization": ["api.internal"]}}
+var Option = cfg.Option{
+	Name:     "Propagation",
+	OnCreate: newPropagation,
+}
+
+func newPropagation(source cfg.Scanner) (interface{}, error) {
+	c := propagation.DefaultConfig()
+	if err := source.Scan(&c); err != nil {
+		return nil, err
+	}
+	propagation.Configure(c)
+	return c, nil
+}
@@ -0,0 +1,32 @@
+package rds
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Dialect int
+
+const (
+	MySQL Dialect = iota
+	Postgres
+)
+
+// Rebind replace '?' placeholders with the form required by dialect
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	sb := &strings.Builder{}
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,23 @@
+package rds
+
+import (
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ScanTime converts a scanned timestamp column, which is []byte from MySQL without parseTime=true in DSN.
+// Text values are parsed in UTC as the default loc of DSN.
+func ScanTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case []byte:
+		return time.ParseInLocation("2006-01-02 15:04:05", string(t), time.UTC)
+	case string:
+		return time.ParseInLocation("2006-01-02 15:04:05", t, time.UTC)
+	case nil:
+		return time.Time{}, nil
+	}
+	return time.Time{}, xerrors.Errorf("unsupported time value: %T", v)
+}
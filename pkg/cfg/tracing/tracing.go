@@ -15,6 +15,7 @@ import (
 
 	"github.com/GotaX/go-server-skeleton/pkg/cfg"
 	"github.com/GotaX/go-server-skeleton/pkg/ext/app"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
 	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
 )
 
@@ -67,6 +68,7 @@ func newTracing(source cfg.Scanner) (interface{}, error) {
 	})
 
 	http.DefaultClient.Transport = &ochttp.Transport{
+		Base:           propagation.Transport(nil),
 		Propagation:    tracing.Propagation,
 		FormatSpanName: formatSpanName,
 	}
@@ -14,6 +14,7 @@ import (
 	"google.golang.org/grpc/metadata"
 
 	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
 )
 
 const DefaultEndpoint = "http://localhost:3500/v1.0"
@@ -23,8 +24,13 @@ type DaprHttp interface {
 }
 
 func NewDaprHttp() DaprHttp {
+	return NewDaprHttpClient(resty.New())
+}
+
+// NewDaprHttpClient uses customized client, e.g. with a circuit breaker transport
+func NewDaprHttpClient(client *resty.Client) DaprHttp {
 	return &daprHttp{
-		Client:   resty.New(),
+		Client:   client,
 		Endpoint: DefaultEndpoint,
 	}
 }
@@ -72,6 +78,12 @@ func headersFromContext(ctx context.Context) map[string]string {
 			headers[key] = content
 		}
 	}
+	// Sidecar is not a listed destination, restricted keys are dropped
+	for key, value := range propagation.FromContext(ctx).To("") {
+		if _, ok := headers[key]; !ok {
+			headers[key] = value
+		}
+	}
 	return headers
 }
 
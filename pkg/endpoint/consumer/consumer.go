@@ -0,0 +1,331 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	driver "github.com/streadway/amqp"
+	"go.opencensus.io/trace"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/amqp"
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+const (
+	HeaderAttempts = "x-attempts"
+	HeaderError    = "x-error"
+
+	suffixRetry      = ".retry"
+	suffixDeadLetter = ".dlq"
+)
+
+type Handler func(ctx context.Context, d driver.Delivery) error
+
+type Consumer struct {
+	Queue   string
+	Handler Handler
+
+	// Unacked messages per worker channel, default 10
+	Prefetch int
+	// Number of concurrent handlers, default 1
+	Concurrency int
+	// Attempts before dead-lettering, default 5
+	MaxAttempts int
+	// Delay before redelivery of retryable failures, default 5s
+	RetryDelay time.Duration
+}
+
+type action int
+
+const (
+	ack action = iota
+	requeue
+	retry
+	deadLetter
+)
+
+// decide maps handler error to delivery action by errors.Code
+func decide(err error) action {
+	if err == nil {
+		return ack
+	}
+	switch errors.Code(err) {
+	case errors.Canceled:
+		return requeue
+	case errors.InvalidArgument, errors.NotFound, errors.AlreadyExists,
+		errors.PermissionDenied, errors.FailedPrecondition, errors.OutOfRange,
+		errors.Unimplemented, errors.DataLoss, errors.Unauthenticated:
+		return deadLetter
+	default:
+		return retry
+	}
+}
+
+type consumers struct {
+	name  string
+	conn  *amqp.Connection
+	items []Consumer
+	// Publishes retry and dead-letter copies in confirm mode, replaced in tests
+	publish func(ctx context.Context, exchange, key string, mandatory bool, msg driver.Publishing) error
+
+	mu      sync.Mutex
+	stopped bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func Endpoint(name string, conn *amqp.Connection, items ...Consumer) endpoint.Endpoint {
+	items = append([]Consumer(nil), items...)
+	for i := range items {
+		c := &items[i]
+		if c.Prefetch <= 0 {
+			c.Prefetch = 10
+		}
+		if c.Concurrency <= 0 {
+			c.Concurrency = 1
+		}
+		if c.MaxAttempts <= 0 {
+			c.MaxAttempts = 5
+		}
+		if c.RetryDelay <= 0 {
+			c.RetryDelay = 5 * time.Second
+		}
+	}
+	return &consumers{
+		name:    name,
+		conn:    conn,
+		items:   items,
+		publish: conn.Publisher(nil, 0).PublishRaw,
+		stop:    make(chan struct{}),
+	}
+}
+
+func (e *consumers) Name() string {
+	return fmt.Sprintf("AMQP (%s), %s", e.conn.Name(), e.name)
+}
+
+func (e *consumers) Run() error {
+	for _, c := range e.items {
+		if err := e.conn.Declare(amqp.Topology{
+			Queues: []amqp.Queue{
+				{
+					Name:    c.Queue + suffixRetry,
+					Durable: true,
+					Args: driver.Table{
+						// Expired messages route back to origin queue via default exchange
+						"x-dead-letter-exchange":    "",
+						"x-dead-letter-routing-key": c.Queue,
+					},
+				},
+				{Name: c.Queue + suffixDeadLetter, Durable: true},
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Stopped before running, Stop may have returned already
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return nil
+	}
+	e.wg.Add(len(e.items))
+	e.mu.Unlock()
+
+	for _, c := range e.items {
+		go func(c Consumer) {
+			defer e.wg.Done()
+			e.consume(c)
+		}(c)
+	}
+
+	e.wg.Wait()
+	return nil
+}
+
+// Stop cancels subscriptions and waits in-flight messages to finish
+func (e *consumers) Stop() error {
+	e.mu.Lock()
+	if !e.stopped {
+		e.stopped = true
+		close(e.stop)
+	}
+	e.mu.Unlock()
+	e.wg.Wait()
+	return nil
+}
+
+func (e *consumers) consume(c Consumer) {
+	entry := logrus.WithFields(logrus.Fields{"name": e.name, "queue": c.Queue})
+	reconnected := e.conn.NotifyReconnect(make(chan struct{}, 1))
+	defer e.conn.StopNotifyReconnect(reconnected)
+	for {
+		if err := e.subscribe(c, entry); err != nil {
+			entry.WithError(err).Warn("Fail to consume, wait for reconnection")
+		}
+
+		select {
+		case <-e.stop:
+			return
+		case <-reconnected:
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// subscribe blocks until subscription closed, returns nil if stopped
+func (e *consumers) subscribe(c Consumer, entry *logrus.Entry) error {
+	ch, err := e.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ch.Close() }()
+
+	if err = ch.Qos(c.Prefetch, 0, false); err != nil {
+		return err
+	}
+
+	tag := fmt.Sprintf("%s-%s", e.name, c.Queue)
+	deliveries, err := ch.Consume(c.Queue, tag, false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	entry.Debug("Consuming...")
+
+	workers := &sync.WaitGroup{}
+	for i := 0; i < c.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for d := range deliveries {
+				e.handle(c, d, entry)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return errors.E(errors.Unavailable, "consumer.subscribe", amqp.ErrNotConnected)
+	case <-e.stop:
+		// Deliveries channel closed after cancel, then workers exit
+		if err = ch.Cancel(tag, false); err != nil {
+			entry.WithError(err).Warn("Fail to cancel consumer")
+		}
+		<-done
+		entry.Debug("Drained")
+		return nil
+	}
+}
+
+func (e *consumers) handle(c Consumer, d driver.Delivery, entry *logrus.Entry) {
+	ctx, span := startSpan(c.Queue, d)
+	defer span.End()
+
+	err := safeCall(ctx, c.Handler, d)
+	entry = entry.WithField("message_id", d.MessageId)
+
+	switch decide(err) {
+	case ack:
+		err = d.Ack(false)
+	case requeue:
+		entry.WithError(err).Info("Requeue message")
+		err = d.Nack(false, true)
+	case retry:
+		attempts := attempts(d) + 1
+		if attempts >= c.MaxAttempts {
+			entry.WithError(err).Warnf("Dead-letter message after %d attempts", attempts)
+			err = e.forward(ctx, d, c.Queue+suffixDeadLetter, attempts, err, 0)
+		} else {
+			entry.WithError(err).Infof("Retry message, attempts: %d", attempts)
+			err = e.forward(ctx, d, c.Queue+suffixRetry, attempts, err, c.RetryDelay)
+		}
+	case deadLetter:
+		entry.WithError(err).Warn("Dead-letter message")
+		err = e.forward(ctx, d, c.Queue+suffixDeadLetter, attempts(d)+1, err, 0)
+	}
+
+	if err != nil {
+		entry.WithError(err).Warn("Fail to settle message")
+	}
+}
+
+// forward republishes delivery to queue, acks it once confirmed by broker, fallback to requeue on failure
+func (e *consumers) forward(ctx context.Context, d driver.Delivery, queue string, attempts int, cause error, delay time.Duration) error {
+	headers := driver.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderAttempts] = int32(attempts)
+	headers[HeaderError] = cause.Error()
+
+	msg := driver.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    driver.Persistent,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+	if delay > 0 {
+		msg.Expiration = strconv.FormatInt(int64(delay/time.Millisecond), 10)
+	}
+
+	// Mandatory, a missing queue returns the message instead of dropping it
+	if err := e.publish(ctx, "", queue, true, msg); err != nil {
+		logrus.WithError(err).
+			WithFields(logrus.Fields{"name": e.name, "queue": queue}).
+			Warn("Fail to forward message, requeue it")
+		return d.Nack(false, true)
+	}
+	return d.Ack(false)
+}
+
+func safeCall(ctx context.Context, handler Handler, d driver.Delivery) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = errors.E(errors.Internal, xerrors.Errorf("panic: %v", p))
+		}
+	}()
+	return handler(ctx, d)
+}
+
+func startSpan(queue string, d driver.Delivery) (context.Context, *trace.Span) {
+	name := "amqp:" + queue
+	if sc, ok := tracing.SpanContextFromHeader(amqp.HeaderFromTable(d.Headers)); ok {
+		return trace.StartSpanWithRemoteParent(context.Background(), name, sc,
+			trace.WithSpanKind(trace.SpanKindServer))
+	}
+	return trace.StartSpan(context.Background(), name, trace.WithSpanKind(trace.SpanKindServer))
+}
+
+func attempts(d driver.Delivery) int {
+	switch v := d.Headers[HeaderAttempts].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,151 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	driver "github.com/streadway/amqp"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/amqp"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+func TestDecide(t *testing.T) {
+	cause := xerrors.New("cause")
+	testCases := []struct {
+		err    error
+		action action
+	}{
+		{nil, ack},
+		{errors.E(errors.Canceled, cause), requeue},
+		{errors.E(errors.Unavailable, cause), retry},
+		{errors.E(errors.Internal, cause), retry},
+		{cause, retry},
+		{errors.E(errors.InvalidArgument, cause), deadLetter},
+		{errors.E(errors.NotFound, cause), deadLetter},
+	}
+
+	for _, c := range testCases {
+		if a := decide(c.err); a != c.action {
+			t.Fatalf("failed to decide %v: expected %v got %v", c.err, c.action, a)
+		}
+	}
+}
+
+type fakeAcknowledger struct {
+	settled string
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.settled = "ack"
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	if a.settled = "nack"; requeue {
+		a.settled = "requeue"
+	}
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestHandle(t *testing.T) {
+	// Not connected, forwarding falls back to requeue
+	e := Endpoint("test", &amqp.Connection{}).(*consumers)
+	entry := logrus.WithField("name", "test")
+
+	testCases := []struct {
+		name    string
+		handler Handler
+		settled string
+	}{
+		{"ok", func(ctx context.Context, d driver.Delivery) error { return nil }, "ack"},
+		{"canceled", func(ctx context.Context, d driver.Delivery) error {
+			return errors.E(errors.Canceled, xerrors.New("canceled"))
+		}, "requeue"},
+		{"panic", func(ctx context.Context, d driver.Delivery) error { panic("boom") }, "requeue"},
+	}
+
+	for _, c := range testCases {
+		a := &fakeAcknowledger{}
+		d := driver.Delivery{Acknowledger: a, Headers: driver.Table{HeaderAttempts: int32(1)}}
+		e.handle(Consumer{Queue: "q", Handler: c.handler, MaxAttempts: 5}, d, entry)
+		if a.settled != c.settled {
+			t.Fatalf("%s: expected %s got %s", c.name, c.settled, a.settled)
+		}
+	}
+
+	if n := attempts(driver.Delivery{Headers: driver.Table{HeaderAttempts: int64(3)}}); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestRetryToDeadLetter(t *testing.T) {
+	e := Endpoint("test", &amqp.Connection{}).(*consumers)
+	entry := logrus.WithField("name", "test")
+
+	var (
+		queues  []string
+		headers = driver.Table{}
+	)
+	e.publish = func(ctx context.Context, exchange, key string, mandatory bool, msg driver.Publishing) error {
+		queues = append(queues, fmt.Sprintf("%s:%v", key, msg.Headers[HeaderAttempts]))
+		headers = msg.Headers
+		if msg.Expiration != "" && msg.Expiration != "1000" {
+			t.Fatalf("unexpected expiration: %s", msg.Expiration)
+		}
+		return nil
+	}
+	c := Consumer{Queue: "q", MaxAttempts: 3, RetryDelay: time.Second, Handler: func(ctx context.Context, d driver.Delivery) error {
+		return errors.E(errors.Unavailable, xerrors.New("unavailable"))
+	}}
+
+	// Redeliver forwarded copies like the retry queue does
+	for i := 0; i < c.MaxAttempts; i++ {
+		a := &fakeAcknowledger{}
+		e.handle(c, driver.Delivery{Acknowledger: a, Headers: headers}, entry)
+		if a.settled != "ack" {
+			t.Fatalf("attempt %d: expected ack after confirm, got %s", i+1, a.settled)
+		}
+	}
+
+	expected := []string{"q.retry:1", "q.retry:2", "q.dlq:3"}
+	if fmt.Sprint(queues) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v got %v", expected, queues)
+	}
+
+	// Not confirmed, keep original message
+	e.publish = func(ctx context.Context, exchange, key string, mandatory bool, msg driver.Publishing) error {
+		return errors.E(errors.Unavailable, amqp.ErrNacked)
+	}
+	a := &fakeAcknowledger{}
+	e.handle(c, driver.Delivery{Acknowledger: a}, entry)
+	if a.settled != "requeue" {
+		t.Fatalf("expected requeue when not confirmed, got %s", a.settled)
+	}
+}
+
+func TestStopBeforeRun(t *testing.T) {
+	e := Endpoint("test", &amqp.Connection{}, Consumer{Queue: "q"})
+	if err := e.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- e.Run() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run consumes after Stop")
+	}
+}
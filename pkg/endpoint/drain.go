@@ -0,0 +1,37 @@
+package endpoint
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/ext/health"
+)
+
+// drain marks readiness failing, waits pre-stop delay, then shuts down in time or force closes
+func (o httpOptions) drain(name string, shutdown func(ctx context.Context) error, force func() error) error {
+	entry := logrus.WithField("name", name)
+
+	health.SetShuttingDown()
+	if o.preStopDelay > 0 {
+		entry.Infof("Readiness failing, wait %v before shutdown", o.preStopDelay)
+		time.Sleep(o.preStopDelay)
+	}
+
+	st := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), o.shutdownTimeout)
+	defer cancel()
+
+	err := shutdown(ctx)
+	if !xerrors.Is(err, context.DeadlineExceeded) {
+		if err == nil {
+			entry.Infof("Drained in %v", time.Since(st).Truncate(time.Millisecond))
+		}
+		return err
+	}
+
+	entry.Warnf("Drain timeout after %v, force close", o.shutdownTimeout)
+	return force()
+}
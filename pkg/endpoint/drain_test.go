@@ -0,0 +1,66 @@
+package endpoint
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/rpc"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/health"
+)
+
+func TestDrain(t *testing.T) {
+	defer health.Unregister("shutdown")
+
+	slow := func() { time.Sleep(300 * time.Millisecond) }
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { slow(); return nil })
+	opts := []HttpOption{HttpPreStopDelay(100 * time.Millisecond), HttpShutdownTimeout(100 * time.Millisecond)}
+
+	testCases := []struct {
+		name     string
+		endpoint Endpoint
+	}{
+		{"http", Http("http", "127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { slow() }), opts...)},
+		{"fiber", Fiber("fiber", "127.0.0.1:0", app, opts...)},
+		{"mux", Mux("mux", "127.0.0.1:0", rpc.NewGrpcServer(func(c *rpc.GrpcConfiguration) {}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { slow() }), opts...)},
+	}
+
+	for _, c := range testCases {
+		health.Unregister("shutdown")
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := WithListener(c.endpoint, lis)
+		go func() { _ = e.Run() }()
+
+		requested := make(chan error, 1)
+		go func() {
+			resp, err := http.Get("http://" + lis.Addr().String())
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			requested <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		st := time.Now()
+		if err = e.Stop(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if elapsed := time.Since(st); elapsed < 200*time.Millisecond || elapsed > 900*time.Millisecond {
+			t.Fatalf("%s: expected pre-stop delay and shutdown timeout, took %v", c.name, elapsed)
+		}
+		if err = health.Check(context.Background())["shutdown"]; err != health.ErrShuttingDown {
+			t.Fatalf("%s: expected shutting down, got %v", c.name, err)
+		}
+		if err = <-requested; err == nil {
+			t.Fatalf("%s: expected active request force closed", c.name)
+		}
+	}
+}
@@ -7,12 +7,18 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"go.opencensus.io/plugin/ochttp"
-	"golang.org/x/sync/errgroup"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/xerrors"
+	driver "google.golang.org/grpc"
 
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/rpc"
 	"github.com/GotaX/go-server-skeleton/pkg/ext/shutdown"
 	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
 )
@@ -23,73 +29,165 @@ type Endpoint interface {
 	Stop() error
 }
 
+// Listener is optionally implemented by endpoints binding address before Run
+type Listener interface {
+	Listen() error
+}
+
+// listenerCloser is implemented by built-in endpoints, releases listener bound by Listen if Run is never called
+type listenerCloser interface {
+	closeListener() error
+}
+
+// Run runs endpoints until shutdown signal or any fails, all endpoints are stopped in both cases.
+// Listeners are inherited from LISTEN_FDS if present, and handed off to new binary on UpgradeSignal.
 func Run(endpoints ...Endpoint) error {
-	group := &errgroup.Group{}
-	for _, endpoint := range endpoints {
-		e := endpoint
-		entry := logrus.WithField("name", e.Name())
+	ctx, cancel := context.WithCancel(context.Background())
+	g := Start(ctx, endpoints...)
+	shutdown.AddHook(func() {
+		cancel()
+		<-g.Done()
+	})
+	defer cancel()
 
-		group.Go(func() error {
-			entry.Debug("Listening ...")
-			return e.Run()
-		})
-
-		shutdown.AddHook(func() {
-			entry.Debug("Shutdown...")
-			if err := e.Stop(); err != nil {
-				entry.WithError(err).Warn("Fail to shutdown")
-			} else {
-				entry.Debug("Stopped")
-			}
-		})
-	}
-	return group.Wait()
+	go notifyReady(g)
+	go watchUpgrade(g)
+	return g.Wait()
 }
 
 type fiberSrv struct {
 	name string
 	addr string
 	app  *fiber.App
+	opts httpOptions
+
+	injected net.Listener
+	mu       sync.Mutex
+	tracked  *trackedListener
+	lis      net.Listener
+	stopped  bool
 }
 
-func Fiber(name, addr string, app *fiber.App) Endpoint {
-	return &fiberSrv{name: name, addr: addr, app: app}
+func Fiber(name, addr string, app *fiber.App, opts ...HttpOption) Endpoint {
+	return &fiberSrv{name: name, addr: addr, app: app, opts: newHttpOptions(opts)}
 }
 
-func (e fiberSrv) Name() string {
+func (e *fiberSrv) Name() string {
 	return fmt.Sprintf("Http (%s), %s", e.addr, e.name)
 }
 
-func (e fiberSrv) Run() error {
-	return e.app.Listen(e.addr)
+func (e *fiberSrv) Listen() (err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lis != nil {
+		return nil
+	}
+
+	lis := e.injected
+	if lis == nil {
+		if lis, err = listen(e.addr); err != nil {
+			return err
+		}
+	}
+	// Tracked beneath TLS, fasthttp still sees *tls.Conn
+	e.tracked = newTrackedListener(lis)
+	e.lis, err = e.opts.config.listen(e.addr, e.tracked)
+	return
+}
+
+func (e *fiberSrv) setListener(lis net.Listener) {
+	e.injected = lis
 }
 
-func (e fiberSrv) Stop() error {
-	return e.app.Shutdown()
+func (e *fiberSrv) closeListener() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lis == nil {
+		return nil
+	}
+	return e.lis.Close()
+}
+
+func (e *fiberSrv) Run() error {
+	if err := e.Listen(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	lis, stopped := e.lis, e.stopped
+	e.mu.Unlock()
+	if stopped {
+		return nil
+	}
+	return e.app.Listener(lis)
+}
+
+func (e *fiberSrv) Stop() error {
+	e.mu.Lock()
+	e.stopped = true
+	lis, tracked := e.lis, e.tracked
+	e.mu.Unlock()
+	// Shutdown returns at once if Listener isn't reached yet, closing makes a late one fail
+	if lis != nil {
+		defer lis.Close()
+	}
+
+	done := make(chan error, 1)
+	shutdown := func(ctx context.Context) error {
+		go func() { done <- e.app.Shutdown() }()
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	// fasthttp can't close active connections, close them beneath
+	force := func() error {
+		if tracked != nil {
+			tracked.closeAll()
+		}
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(time.Second):
+			return xerrors.New("handlers still running after force close")
+		}
+	}
+	return e.opts.drain(e.Name(), shutdown, force)
 }
 
 type rest struct {
 	name string
 	srv  *http.Server
+	opts httpOptions
+
+	injected net.Listener
+	lis      net.Listener
+}
+
+func Http(name, addr string, handler http.Handler, opts ...HttpOption) Endpoint {
+	e := &rest{name: name, opts: newHttpOptions(opts)}
+
+	handler = newTraceHandler(handler)
+	if e.opts.config.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+	e.srv = &http.Server{Addr: addr, Handler: handler}
+	e.opts.config.apply(e.srv)
+	return e
 }
 
-func Http(name, addr string, handler http.Handler) Endpoint {
-	return &rest{
-		name: name,
-		srv: &http.Server{
-			Addr: addr,
-			Handler: &ochttp.Handler{
-				Handler:          handler,
-				Propagation:      tracing.Propagation,
-				FormatSpanName:   newSpanNameFormatter(),
-				IsHealthEndpoint: newHealthEndpoint(),
-			},
-		},
+func newTraceHandler(handler http.Handler) http.Handler {
+	return &ochttp.Handler{
+		Handler:          handler,
+		Propagation:      tracing.Propagation,
+		FormatSpanName:   newSpanNameFormatter(),
+		IsHealthEndpoint: newHealthEndpoint(),
 	}
 }
 
 func newHealthEndpoint() func(*http.Request) bool {
-	endpoints := []string{"/metrics", "/debug/pprof"}
+	endpoints := []string{"/metrics", "/health", "/debug/pprof"}
 	return func(req *http.Request) bool {
 		path := strings.TrimSuffix(req.URL.Path, "/")
 		for _, endpoint := range endpoints {
@@ -116,16 +214,37 @@ func newSpanNameFormatter() func(*http.Request) string {
 	}
 }
 
-func (e rest) Name() string {
+func (e *rest) Name() string {
 	return fmt.Sprintf("Http (%s), %s", e.srv.Addr, e.name)
 }
 
-func (e rest) Run() error {
-	return e.srv.ListenAndServe()
+func (e *rest) Listen() (err error) {
+	if e.lis == nil {
+		e.lis, err = e.opts.config.listen(e.srv.Addr, e.injected)
+	}
+	return
 }
 
-func (e rest) Stop() error {
-	return e.srv.Shutdown(context.Background())
+func (e *rest) setListener(lis net.Listener) {
+	e.injected = lis
+}
+
+func (e *rest) closeListener() error {
+	if e.lis == nil {
+		return nil
+	}
+	return e.lis.Close()
+}
+
+func (e *rest) Run() error {
+	if err := e.Listen(); err != nil {
+		return err
+	}
+	return e.srv.Serve(e.lis)
+}
+
+func (e *rest) Stop() error {
+	return e.opts.drain(e.Name(), e.srv.Shutdown, e.srv.Close)
 }
 
 type GrpcServer interface {
@@ -133,33 +252,100 @@ type GrpcServer interface {
 	Stop()
 }
 
+// Implemented by *grpc.Server
+type gracefulServer interface {
+	GracefulStop()
+}
+
 type grpc struct {
-	name string
-	addr string
-	srv  GrpcServer
+	name         string
+	addr         string
+	srv          GrpcServer
+	drainTimeout time.Duration
+	lis          net.Listener
 }
 
-func Grpc(name, address string, server GrpcServer) Endpoint {
-	return &grpc{
-		name: name,
-		addr: address,
-		srv:  server,
+type GrpcOption func(e *grpc)
+
+// GrpcDrainTimeout limits graceful stop before force stop, default 10s
+func GrpcDrainTimeout(d time.Duration) GrpcOption {
+	return func(e *grpc) { e.drainTimeout = d }
+}
+
+func Grpc(name, address string, server GrpcServer, opts ...GrpcOption) Endpoint {
+	e := &grpc{
+		name:         name,
+		addr:         address,
+		srv:          server,
+		drainTimeout: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(e)
 	}
+	return e
 }
 
-func (e grpc) Name() string {
+func (e *grpc) Name() string {
 	return fmt.Sprintf("GRPC (%s), %s", e.addr, e.name)
 }
 
-func (e grpc) Run() error {
-	if lis, err := net.Listen("tcp", e.addr); err != nil {
+func (e *grpc) Listen() (err error) {
+	if e.lis == nil {
+		e.lis, err = listen(e.addr)
+	}
+	return
+}
+
+func (e *grpc) setListener(lis net.Listener) {
+	e.lis = lis
+}
+
+func (e *grpc) closeListener() error {
+	if e.lis == nil {
+		return nil
+	}
+	return e.lis.Close()
+}
+
+func (e *grpc) Run() error {
+	if err := e.Listen(); err != nil {
 		return err
-	} else {
-		return e.srv.Serve(lis)
 	}
+	return e.srv.Serve(e.lis)
 }
 
-func (e grpc) Stop() error {
-	e.srv.Stop()
+// Stop reports NOT_SERVING, then stops gracefully, force stops remaining calls after drain timeout
+func (e *grpc) Stop() error {
+	gs, ok := e.srv.(gracefulServer)
+	if !ok {
+		e.srv.Stop()
+		return nil
+	}
+
+	s, _ := e.srv.(*driver.Server)
+	if s != nil {
+		rpc.SetNotServing(s)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(done)
+	}()
+
+	timer := time.NewTimer(e.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		entry := logrus.WithField("name", e.Name())
+		if s != nil {
+			entry = entry.WithField("active", rpc.ActiveCalls(s))
+		}
+		entry.Warnf("Drain timeout after %v, force stop", e.drainTimeout)
+		e.srv.Stop()
+		<-done
+	}
 	return nil
 }
@@ -0,0 +1,135 @@
+package endpoint
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/xerrors"
+)
+
+// ContextRunner is optionally implemented by endpoints running until ctx is done, preferred over Run by Start
+type ContextRunner interface {
+	RunContext(ctx context.Context) error
+}
+
+// Group runs endpoints together, all of them are stopped once any fails or ctx is done
+type Group struct {
+	started chan struct{}
+	done    chan struct{}
+	err     error
+}
+
+// Start runs endpoints in background, use Wait for the result
+func Start(ctx context.Context, endpoints ...Endpoint) *Group {
+	g := &Group{started: make(chan struct{}), done: make(chan struct{})}
+	runners, ctx := errgroup.WithContext(ctx)
+
+	var (
+		bound  sync.WaitGroup
+		failed int32
+	)
+	bound.Add(len(endpoints))
+	for _, endpoint := range endpoints {
+		e := endpoint
+		entry := logrus.WithField("name", e.Name())
+
+		runners.Go(func() error {
+			if l, ok := e.(Listener); ok {
+				if err := l.Listen(); err != nil {
+					atomic.StoreInt32(&failed, 1)
+					bound.Done()
+					return xerrors.Errorf("%s: %w", e.Name(), err)
+				}
+			}
+			bound.Done()
+			// Others failed while listening, Stop may have returned already, nothing closes the listener then
+			if ctx.Err() != nil {
+				if c, ok := e.(listenerCloser); ok {
+					if err := c.closeListener(); err != nil {
+						entry.WithError(err).Debug("Fail to close listener")
+					}
+				}
+				return nil
+			}
+
+			entry.Debug("Listening ...")
+			var err error
+			if r, ok := e.(ContextRunner); ok {
+				err = r.RunContext(ctx)
+			} else {
+				err = e.Run()
+			}
+			// Errors after stopping, e.g. http.ErrServerClosed, are expected
+			if err != nil && ctx.Err() == nil {
+				return xerrors.Errorf("%s: %w", e.Name(), err)
+			}
+			return nil
+		})
+	}
+
+	go func() {
+		bound.Wait()
+		if atomic.LoadInt32(&failed) == 0 {
+			close(g.started)
+		}
+	}()
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		stopAll(endpoints)
+	}()
+
+	go func() {
+		// Context of runners is canceled once Wait returns
+		g.err = runners.Wait()
+		<-stopped
+		close(g.done)
+	}()
+	return g
+}
+
+// RunContext runs endpoints until ctx is done or any fails
+func RunContext(ctx context.Context, endpoints ...Endpoint) error {
+	return Start(ctx, endpoints...).Wait()
+}
+
+// Started is closed once all listeners are bound, never closed if any fails to listen
+func (g *Group) Started() <-chan struct{} {
+	return g.started
+}
+
+// Done is closed once all endpoints are stopped
+func (g *Group) Done() <-chan struct{} {
+	return g.done
+}
+
+// Wait returns first error of endpoints after all are stopped
+func (g *Group) Wait() error {
+	<-g.done
+	return g.err
+}
+
+func stopAll(endpoints []Endpoint) {
+	wg := sync.WaitGroup{}
+	for _, endpoint := range endpoints {
+		e := endpoint
+		entry := logrus.WithField("name", e.Name())
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry.Debug("Shutdown...")
+			if err := e.Stop(); err != nil {
+				entry.WithError(err).Warn("Fail to shutdown")
+			} else {
+				entry.Debug("Stopped")
+			}
+		}()
+	}
+	wg.Wait()
+}
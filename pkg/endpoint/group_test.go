@@ -0,0 +1,135 @@
+package endpoint
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	driver "google.golang.org/grpc"
+
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/rpc"
+)
+
+func TestGroup(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	handler := http.NotFoundHandler()
+
+	// Fails fast if any fails to listen
+	g := Start(context.Background(), Http("a", "127.0.0.1:0", handler), Http("b", lis.Addr().String(), handler))
+	select {
+	case <-g.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for failure")
+	}
+	if g.Wait() == nil {
+		t.Fatal("expected error of address in use")
+	}
+
+	// Stops all once ctx is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	g = Start(ctx, Http("a", "127.0.0.1:0", handler), Http("b", "127.0.0.1:0", handler))
+	select {
+	case <-g.Started():
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for started")
+	}
+	cancel()
+	if err = g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGroupCanceledBeforeRun(t *testing.T) {
+	newGrpc := func() *driver.Server { return rpc.NewGrpcServer(func(c *rpc.GrpcConfiguration) {}) }
+	endpoints := map[string]func() Endpoint{
+		"http":  func() Endpoint { return Http("http", "", http.NotFoundHandler()) },
+		"grpc":  func() Endpoint { return Grpc("grpc", "", newGrpc()) },
+		"mux":   func() Endpoint { return Mux("mux", "", newGrpc(), http.NotFoundHandler()) },
+		"fiber": func() Endpoint { return Fiber("fiber", "", fiber.New()) },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, newEndpoint := range endpoints {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = Start(ctx, WithListener(newEndpoint(), lis)).Wait(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Never served, listener is closed by Start
+		accepted := make(chan error, 1)
+		go func() {
+			conn, err := lis.Accept()
+			if err == nil {
+				_ = conn.Close()
+			}
+			accepted <- err
+		}()
+		select {
+		case err = <-accepted:
+			if err == nil {
+				t.Fatalf("%s: listener still open", name)
+			}
+		case <-time.After(time.Second):
+			_ = lis.Close()
+			t.Fatalf("%s: listener still open", name)
+		}
+	}
+}
+
+type ctxRunner struct {
+	stopped bool
+}
+
+func (r *ctxRunner) Name() string { return "ctx" }
+func (r *ctxRunner) Run() error   { select {} }
+func (r *ctxRunner) Stop() error  { r.stopped = true; return nil }
+
+func (r *ctxRunner) RunContext(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestGroupRunContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &ctxRunner{}
+	g := Start(ctx, r)
+	cancel()
+
+	select {
+	case <-g.Done():
+	case <-time.After(time.Second):
+		t.Fatal("RunContext isn't preferred over Run")
+	}
+	if !r.stopped {
+		t.Fatal("expected stopped")
+	}
+}
+
+func TestFiberStopBeforeRun(t *testing.T) {
+	e := Fiber("fiber", "127.0.0.1:0", fiber.New())
+	if err := e.(Listener).Listen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- e.Run() }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run serves after Stop")
+	}
+}
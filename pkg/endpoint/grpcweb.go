@@ -0,0 +1,128 @@
+package endpoint
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	contentTypeGrpc    = "application/grpc"
+	contentTypeGrpcWeb = "application/grpc-web"
+	contentTypeText    = "application/grpc-web-text"
+)
+
+func isGrpcWeb(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGrpcWeb)
+}
+
+// grpcWebHandler translates gRPC-Web (binary or text) to gRPC served by next, next must accept HTTP/2 requests
+func grpcWebHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ct := r.Header.Get("Content-Type")
+		text := strings.HasPrefix(ct, contentTypeText)
+
+		req := r.Clone(r.Context())
+		req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2"
+		req.Header.Set("Content-Type", contentTypeGrpc+strings.TrimPrefix(strings.TrimPrefix(ct, contentTypeText), contentTypeGrpcWeb))
+		req.Header.Del("Content-Length")
+		if text {
+			req.Body = ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, r.Body))
+		}
+
+		ww := newGrpcWebWriter(w, ct, text)
+		next.ServeHTTP(ww, req)
+		ww.finish()
+	})
+}
+
+type grpcWebWriter struct {
+	w           http.ResponseWriter
+	out         io.Writer
+	encoder     io.WriteCloser
+	header      http.Header
+	contentType string
+	wroteHeader bool
+}
+
+func newGrpcWebWriter(w http.ResponseWriter, contentType string, text bool) *grpcWebWriter {
+	ww := &grpcWebWriter{w: w, out: w, header: http.Header{}, contentType: contentType}
+	if text {
+		ww.encoder = base64.NewEncoder(base64.StdEncoding, w)
+		ww.out = ww.encoder
+	}
+	return ww
+}
+
+func (ww *grpcWebWriter) Header() http.Header {
+	return ww.header
+}
+
+func (ww *grpcWebWriter) WriteHeader(code int) {
+	if ww.wroteHeader {
+		return
+	}
+	ww.wroteHeader = true
+
+	h := ww.w.Header()
+	for key, values := range ww.header {
+		if key != "Trailer" {
+			h[key] = values
+		}
+	}
+	h.Set("Content-Type", ww.contentType)
+	h.Del("Content-Length")
+	ww.w.WriteHeader(code)
+}
+
+func (ww *grpcWebWriter) Write(b []byte) (int, error) {
+	ww.WriteHeader(http.StatusOK)
+	return ww.out.Write(b)
+}
+
+func (ww *grpcWebWriter) Flush() {
+	ww.WriteHeader(http.StatusOK)
+	if f, ok := ww.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes trailers as the last frame
+func (ww *grpcWebWriter) finish() {
+	ww.WriteHeader(http.StatusOK)
+
+	var names []string
+	for _, declared := range ww.header["Trailer"] {
+		for _, name := range strings.Split(declared, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	for key := range ww.header {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			names = append(names, strings.TrimPrefix(key, http.TrailerPrefix))
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	for _, name := range names {
+		values := ww.header.Values(name)
+		if len(values) == 0 {
+			values = ww.header.Values(http.TrailerPrefix + name)
+		}
+		for _, value := range values {
+			buf.WriteString(strings.ToLower(name) + ": " + value + "\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = 1 << 7
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	_, _ = ww.out.Write(append(frame, buf.Bytes()...))
+	if ww.encoder != nil {
+		_ = ww.encoder.Close()
+	}
+}
@@ -0,0 +1,129 @@
+package endpoint
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Inherited sockets follow systemd socket activation, starting from fd 3
+const (
+	envListenFds   = "LISTEN_FDS"
+	envListenPid   = "LISTEN_PID"
+	listenFdsStart = 3
+)
+
+var (
+	inheritOnce sync.Once
+	sockets     = &socketSet{}
+)
+
+// socketSet holds inherited but not yet used listeners and all bound ones
+type socketSet struct {
+	mu        sync.Mutex
+	inherited []net.Listener
+	bound     []net.Listener
+}
+
+// listen takes inherited listener of addr if any, otherwise binds a new one
+func listen(addr string) (net.Listener, error) {
+	network, address, mode, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	inheritOnce.Do(func() { sockets.inherited = inheritListeners() })
+
+	sockets.mu.Lock()
+	defer sockets.mu.Unlock()
+
+	for i, lis := range sockets.inherited {
+		if sameAddr(lis.Addr(), network, address) {
+			sockets.inherited = append(sockets.inherited[:i], sockets.inherited[i+1:]...)
+			sockets.bound = append(sockets.bound, lis)
+			logrus.WithField("addr", lis.Addr().String()).Debug("Use inherited listener")
+			return lis, nil
+		}
+	}
+
+	lis, err := bind(network, address, mode)
+	if err != nil {
+		return nil, err
+	}
+	sockets.bound = append(sockets.bound, lis)
+	return lis, nil
+}
+
+// boundFiles duplicates descriptors of bound listeners for handoff, closed listeners are skipped
+func boundFiles() (files []*os.File) {
+	sockets.mu.Lock()
+	defer sockets.mu.Unlock()
+
+	for _, lis := range sockets.bound {
+		// Socket file is used by new process
+		if ul, ok := lis.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+		filer, ok := lis.(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+		if f, err := filer.File(); err == nil {
+			files = append(files, f)
+		}
+	}
+	return
+}
+
+// inheritListeners accepts LISTEN_FDS from systemd (LISTEN_PID matches) or parent process (LISTEN_PID absent)
+func inheritListeners() (result []net.Listener) {
+	n, err := strconv.Atoi(os.Getenv(envListenFds))
+	if err != nil || n <= 0 {
+		return nil
+	}
+	if pid := os.Getenv(envListenPid); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil
+	}
+	// Not for children
+	_ = os.Unsetenv(envListenFds)
+	_ = os.Unsetenv(envListenPid)
+
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "listener-"+strconv.Itoa(fd))
+		lis, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			logrus.WithError(err).Warnf("Fail to inherit fd %d", fd)
+			continue
+		}
+		result = append(result, lis)
+	}
+	return
+}
+
+// sameAddr compares address of inherited listener with requested one, e.g. "[::]:8080" and ":8080"
+func sameAddr(a net.Addr, network, addr string) bool {
+	// "tcp4" and "tcp6" listeners report "tcp"
+	if !strings.HasPrefix(network, a.Network()) {
+		return false
+	}
+	if a.String() == addr {
+		return true
+	}
+
+	ta, ok := a.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	want, err := net.ResolveTCPAddr(network, addr)
+	if err != nil || want.Port != ta.Port {
+		return false
+	}
+	if len(want.IP) == 0 || want.IP.IsUnspecified() {
+		return ta.IP.IsUnspecified()
+	}
+	return want.IP.Equal(ta.IP)
+}
@@ -0,0 +1,28 @@
+package endpoint
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSameAddr(t *testing.T) {
+	testCases := []struct {
+		addr     net.Addr
+		network  string
+		want     string
+		expected bool
+	}{
+		{&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", ":8080", true},
+		{&net.TCPAddr{IP: net.IPv4zero, Port: 8080}, "tcp", "0.0.0.0:8080", true},
+		{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, "tcp", "127.0.0.1:8080", true},
+		{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, "tcp", ":8080", false},
+		{&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", ":8081", false},
+		{&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "unix", ":8080", false},
+	}
+
+	for _, c := range testCases {
+		if actual := sameAddr(c.addr, c.network, c.want); actual != c.expected {
+			t.Fatalf("%s vs %s %s: expected %v", c.addr, c.network, c.want, c.expected)
+		}
+	}
+}
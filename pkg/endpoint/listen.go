@@ -0,0 +1,75 @@
+package endpoint
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const schemeUnix = "unix://"
+
+// parseAddr accepts "host:port" or "unix:///abs/path?mode=0660" ("unix://rel/path" for relative path)
+func parseAddr(addr string) (network, address string, mode os.FileMode, err error) {
+	if !strings.HasPrefix(addr, schemeUnix) {
+		return "tcp", addr, 0, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if address = u.Host + u.Path; address == "" {
+		return "", "", 0, xerrors.Errorf("invalid address: %q", addr)
+	}
+	if str := u.Query().Get("mode"); str != "" {
+		v, err := strconv.ParseUint(str, 8, 32)
+		if err != nil {
+			return "", "", 0, xerrors.Errorf("invalid mode of %q: %w", addr, err)
+		}
+		mode = os.FileMode(v)
+	}
+	return "unix", address, mode, nil
+}
+
+// bind listens on address, stale unix socket file is removed before and mode is applied after
+func bind(network, address string, mode os.FileMode) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+
+	if fi, err := os.Stat(address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.DialTimeout(network, address, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, xerrors.Errorf("socket in use: %s", address)
+		}
+		if err = os.Remove(address); err != nil {
+			return nil, err
+		}
+	}
+
+	lis, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err = os.Chmod(address, mode); err != nil {
+			_ = lis.Close()
+			return nil, err
+		}
+	}
+	return lis, nil
+}
+
+// WithListener makes built-in endpoint serve on lis instead of binding address,
+// e.g. for tests with ":0", sidecars or bufconn
+func WithListener(e Endpoint, lis net.Listener) Endpoint {
+	if s, ok := e.(interface{ setListener(net.Listener) }); ok {
+		s.setListener(lis)
+	}
+	return e
+}
@@ -0,0 +1,100 @@
+package endpoint
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseAddr(t *testing.T) {
+	testCases := []struct {
+		addr    string
+		network string
+		address string
+		mode    os.FileMode
+	}{
+		{":8080", "tcp", ":8080", 0},
+		{"unix:///run/app.sock", "unix", "/run/app.sock", 0},
+		{"unix:///run/app.sock?mode=0660", "unix", "/run/app.sock", 0660},
+		{"unix://app.sock", "unix", "app.sock", 0},
+	}
+
+	for _, c := range testCases {
+		network, address, mode, err := parseAddr(c.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if network != c.network || address != c.address || mode != c.mode {
+			t.Fatalf("%s: unexpected %s %s %o", c.addr, network, address, mode)
+		}
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.sock")
+
+	// Stale socket file is removed
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g := Start(ctx, Http("unix", "unix://"+path+"?mode=0600", http.NotFoundHandler()))
+	select {
+	case <-g.Started():
+	case <-g.Done():
+		t.Fatal(g.Wait())
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for started")
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected mode: %o", fi.Mode().Perm())
+	}
+
+	cancel()
+	if err = g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("socket file not removed: %v", err)
+	}
+}
+
+func TestWithListener(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := Start(ctx, WithListener(Http("injected", "", http.NotFoundHandler()), lis))
+	<-g.Started()
+
+	resp, err := http.Get("http://" + lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+}
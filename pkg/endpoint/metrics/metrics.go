@@ -5,11 +5,14 @@ import (
 	"net/http/pprof"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/GotaX/go-server-skeleton/pkg/ext/health"
 )
 
 func Router() http.Handler {
 	mux := &http.ServeMux{}
 	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/health", health.Handler())
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
@@ -0,0 +1,170 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	driver "google.golang.org/grpc"
+
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/rpc"
+)
+
+type mux struct {
+	name     string
+	srv      *http.Server
+	grpc     *driver.Server
+	opts     httpOptions
+	injected net.Listener
+	lis      net.Listener
+}
+
+// Mux serves gRPC (HTTP/2 or h2c), gRPC-Web and plain HTTP on a single address.
+// Requests are routed by content type, don't serve grpcServer by other endpoints.
+// h2c is always enabled, WriteTimeout of HttpConfig bounds gRPC streams too.
+func Mux(name, addr string, grpcServer *driver.Server, handler http.Handler, opts ...HttpOption) Endpoint {
+	grpcWeb := grpcWebHandler(grpcServer)
+	web := newTraceHandler(handler)
+
+	route := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case isGrpcWeb(r):
+			grpcWeb.ServeHTTP(w, r)
+		case r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGrpc):
+			grpcServer.ServeHTTP(w, r)
+		default:
+			web.ServeHTTP(w, r)
+		}
+	})
+
+	e := &mux{
+		name: name,
+		srv: &http.Server{
+			Addr:    addr,
+			Handler: h2c.NewHandler(route, &http2.Server{}),
+		},
+		grpc: grpcServer,
+		opts: newHttpOptions(opts),
+	}
+	e.opts.config.apply(e.srv)
+	return e
+}
+
+// FiberHandler serves app by net/http to mount it on a Mux, requests over bodyLimit bytes are rejected.
+// Bodies are buffered both ways, don't use it for streaming.
+func FiberHandler(app *fiber.App, bodyLimit int64) http.Handler {
+	if bodyLimit <= 0 {
+		bodyLimit = 4 * 1024 * 1024
+	}
+
+	handler := app.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, bodyLimit+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > bodyLimit {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.Header.SetMethod(r.Method)
+		req.SetRequestURI(r.URL.RequestURI())
+		req.Header.SetHost(r.Host)
+		for key, values := range r.Header {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+		req.SetBody(body)
+
+		var remoteAddr net.Addr
+		if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+			remoteAddr = addr
+		}
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Init(req, remoteAddr, nil)
+		handler(ctx)
+
+		ctx.Response.Header.VisitAll(func(key, value []byte) {
+			w.Header().Add(string(key), string(value))
+		})
+		w.WriteHeader(ctx.Response.StatusCode())
+		_, _ = w.Write(ctx.Response.Body())
+	})
+}
+
+func (e *mux) Name() string {
+	return fmt.Sprintf("Mux (%s), %s", e.srv.Addr, e.name)
+}
+
+func (e *mux) Listen() (err error) {
+	if e.lis == nil {
+		e.lis, err = e.opts.config.listen(e.srv.Addr, e.injected)
+	}
+	return
+}
+
+func (e *mux) setListener(lis net.Listener) {
+	e.injected = lis
+}
+
+func (e *mux) closeListener() error {
+	if e.lis == nil {
+		return nil
+	}
+	return e.lis.Close()
+}
+
+func (e *mux) Run() error {
+	if err := e.Listen(); err != nil {
+		return err
+	}
+	return e.srv.Serve(e.lis)
+}
+
+// Stop reports NOT_SERVING, waits for HTTP requests and gRPC calls, force stops after shutdown timeout
+func (e *mux) Stop() error {
+	rpc.SetNotServing(e.grpc)
+
+	// Connections upgraded to h2c are hijacked, only calls counting tells if gRPC is drained
+	shutdown := func(ctx context.Context) error {
+		if err := e.srv.Shutdown(ctx); err != nil {
+			return err
+		}
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for rpc.ActiveCalls(e.grpc) > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+		return nil
+	}
+	force := func() error {
+		logrus.WithField("name", e.Name()).
+			WithField("active", rpc.ActiveCalls(e.grpc)).
+			Debug("Force stop")
+		return e.srv.Close()
+	}
+
+	err := e.opts.drain(e.Name(), shutdown, force)
+	// GracefulStop doesn't support transports of ServeHTTP
+	e.grpc.Stop()
+	return err
+}
@@ -0,0 +1,160 @@
+package endpoint
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/protobuf/proto"
+	driver "google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/rpc"
+)
+
+func newTestMux() (*mux, func()) {
+	s := rpc.NewGrpcServer(func(c *rpc.GrpcConfiguration) {})
+	web := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("web")) })
+	e := Mux("test", "", s, web).(*mux)
+	return e, s.Stop
+}
+
+func TestMuxGrpc(t *testing.T) {
+	e, stop := newTestMux()
+	defer stop()
+	ts := httptest.NewServer(e.srv.Handler)
+	defer ts.Close()
+
+	conn, err := driver.Dial(strings.TrimPrefix(ts.URL, "http://"), driver.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("unexpected status: %v", resp.Status)
+	}
+
+	r, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected http status: %d", r.StatusCode)
+	}
+}
+
+func TestMuxGrpcWeb(t *testing.T) {
+	e, stop := newTestMux()
+	defer stop()
+
+	data, _ := proto.Marshal(&healthpb.HealthCheckRequest{})
+	frame := make([]byte, 5)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	body := base64.StdEncoding.EncodeToString(append(frame, data...))
+
+	req := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/grpc-web-text")
+	w := httptest.NewRecorder()
+	e.srv.Handler.ServeHTTP(w, req)
+
+	out, err := base64.StdEncoding.DecodeString(w.Body.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/grpc-web-text" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	resp := &healthpb.HealthCheckResponse{}
+	n := binary.BigEndian.Uint32(out[1:5])
+	if err = proto.Unmarshal(out[5:5+n], resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("unexpected status: %v", resp.Status)
+	}
+	if trailer := out[5+n:]; trailer[0] != 1<<7 || !bytes.Contains(trailer, []byte("grpc-status: 0\r\n")) {
+		t.Fatalf("unexpected trailer: %q", trailer)
+	}
+}
+
+func TestMuxConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := writeCert(t, dir)
+
+	s := rpc.NewGrpcServer(func(c *rpc.GrpcConfiguration) {})
+	web := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("web")) })
+	config := HttpConfig{ReadTimeout: cfg.Duration(30 * time.Second), MaxHeaderBytes: 4096, TLS: &files}
+	e := Mux("test", "127.0.0.1:0", s, web, HttpWithConfig(config)).(*mux)
+	if e.srv.ReadTimeout != 30*time.Second || e.srv.MaxHeaderBytes != 4096 {
+		t.Fatalf("unexpected server: %+v", e.srv)
+	}
+
+	if err = e.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = e.Run() }()
+	defer func() { _ = e.Stop() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	r, err := client.Get("https://" + e.lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	if body, _ := ioutil.ReadAll(r.Body); string(body) != "web" || r.ProtoMajor != 2 {
+		t.Fatalf("unexpected response: %s %q", r.Proto, body)
+	}
+}
+
+func TestFiberHandler(t *testing.T) {
+	app := fiber.New()
+	app.Post("/echo", func(ctx *fiber.Ctx) error {
+		ctx.Set("X-Query", ctx.Query("q"))
+		return ctx.Status(http.StatusCreated).Send(ctx.Body())
+	})
+	handler := FiberHandler(app, 8)
+
+	testCases := []struct {
+		body string
+		code int
+	}{
+		{"hello", http.StatusCreated},
+		{"hello world", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range testCases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/echo?q=1", strings.NewReader(c.body)))
+		if w.Code != c.code {
+			t.Fatalf("%q: expected %d got %d", c.body, c.code, w.Code)
+		}
+		if c.code == http.StatusCreated && (w.Body.String() != c.body || w.Header().Get("X-Query") != "1") {
+			t.Fatalf("%q: unexpected response %s %v", c.body, w.Body, w.Header())
+		}
+	}
+}
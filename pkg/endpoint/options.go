@@ -0,0 +1,128 @@
+package endpoint
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/net/netutil"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/certs"
+)
+
+// HttpConfig tunes servers of Http and Fiber, zero values use defaults
+type HttpConfig struct {
+	// Default 10s
+	ReadHeaderTimeout cfg.Duration `json:"readHeaderTimeout"`
+	ReadTimeout       cfg.Duration `json:"readTimeout"`
+	WriteTimeout      cfg.Duration `json:"writeTimeout"`
+	// Default 2m
+	IdleTimeout    cfg.Duration `json:"idleTimeout"`
+	MaxHeaderBytes int          `json:"maxHeaderBytes"`
+	// Limits concurrent connections, unlimited if 0
+	MaxConnections int `json:"maxConnections"`
+	// Serves HTTP/2 without TLS, net/http only
+	H2C bool `json:"h2c"`
+	// TLS or mTLS with hot reload, plain text if nil
+	TLS *certs.Files `json:"tls"`
+}
+
+func (c HttpConfig) readHeaderTimeout() time.Duration {
+	if c.ReadHeaderTimeout > 0 {
+		return c.ReadHeaderTimeout.Std()
+	}
+	return 10 * time.Second
+}
+
+func (c HttpConfig) idleTimeout() time.Duration {
+	if c.IdleTimeout > 0 {
+		return c.IdleTimeout.Std()
+	}
+	return 2 * time.Minute
+}
+
+// FiberConfig applies timeouts and limits to base, pass the result to fiber.New
+func (c HttpConfig) FiberConfig(base fiber.Config) fiber.Config {
+	if c.ReadTimeout > 0 {
+		base.ReadTimeout = c.ReadTimeout.Std()
+	} else {
+		base.ReadTimeout = c.readHeaderTimeout()
+	}
+	base.WriteTimeout = c.WriteTimeout.Std()
+	base.IdleTimeout = c.idleTimeout()
+	// Request headers must fit in read buffer
+	if c.MaxHeaderBytes > 0 {
+		base.ReadBufferSize = c.MaxHeaderBytes
+	}
+	if c.MaxConnections > 0 {
+		base.Concurrency = c.MaxConnections
+	}
+	return base
+}
+
+func (c HttpConfig) apply(srv *http.Server) {
+	srv.ReadHeaderTimeout = c.readHeaderTimeout()
+	srv.ReadTimeout = c.ReadTimeout.Std()
+	srv.WriteTimeout = c.WriteTimeout.Std()
+	srv.IdleTimeout = c.idleTimeout()
+	srv.MaxHeaderBytes = c.MaxHeaderBytes
+}
+
+// listen binds addr unless lis is given, then applies connection limit and TLS
+func (c HttpConfig) listen(addr string, lis net.Listener) (net.Listener, error) {
+	var tc *tls.Config
+	if c.TLS != nil {
+		var err error
+		if tc, err = c.TLS.ServerConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	if lis == nil {
+		var err error
+		if lis, err = listen(addr); err != nil {
+			return nil, err
+		}
+	}
+	if c.MaxConnections > 0 {
+		lis = netutil.LimitListener(lis, c.MaxConnections)
+	}
+	if tc != nil {
+		lis = tls.NewListener(lis, tc)
+	}
+	return lis, nil
+}
+
+type HttpOption func(o *httpOptions)
+
+type httpOptions struct {
+	preStopDelay    time.Duration
+	shutdownTimeout time.Duration
+	config          HttpConfig
+}
+
+// HttpPreStopDelay waits after readiness failing for load balancers to deregister, default 0
+func HttpPreStopDelay(d time.Duration) HttpOption {
+	return func(o *httpOptions) { o.preStopDelay = d }
+}
+
+// HttpShutdownTimeout limits graceful shutdown before force close, default 10s
+func HttpShutdownTimeout(d time.Duration) HttpOption {
+	return func(o *httpOptions) { o.shutdownTimeout = d }
+}
+
+// HttpWithConfig sets timeouts, limits and TLS, use HttpConfig.FiberConfig for timeouts of Fiber
+func HttpWithConfig(c HttpConfig) HttpOption {
+	return func(o *httpOptions) { o.config = c }
+}
+
+func newHttpOptions(opts []HttpOption) httpOptions {
+	o := httpOptions{shutdownTimeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,151 @@
+package endpoint
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/GotaX/go-server-skeleton/pkg/ext/certs"
+)
+
+func TestHttpConfig(t *testing.T) {
+	var c HttpConfig
+	if err := json.Unmarshal([]byte(`{"readTimeout": "30s", "idleTimeout": "1m", "maxHeaderBytes": 4096}`), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{}
+	c.apply(srv)
+	if srv.ReadHeaderTimeout != 10*time.Second || srv.ReadTimeout != 30*time.Second ||
+		srv.IdleTimeout != time.Minute || srv.MaxHeaderBytes != 4096 {
+		t.Fatalf("unexpected server: %+v", srv)
+	}
+
+	fc := c.FiberConfig(fiber.Config{})
+	if fc.ReadTimeout != 30*time.Second || fc.IdleTimeout != time.Minute || fc.ReadBufferSize != 4096 {
+		t.Fatalf("unexpected fiber config: %+v", fc)
+	}
+	if fc = (HttpConfig{}).FiberConfig(fiber.Config{}); fc.ReadTimeout != 10*time.Second || fc.IdleTimeout != 2*time.Minute {
+		t.Fatalf("unexpected default fiber config: %+v", fc)
+	}
+}
+
+func TestListenLimit(t *testing.T) {
+	lis, err := HttpConfig{MaxConnections: 1}.listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", lis.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+	}
+
+	first, err := lis.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := lis.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	select {
+	case <-accepted:
+		t.Fatal("accepted over limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+	_ = first.Close()
+	select {
+	case conn := <-accepted:
+		_ = conn.Close()
+	case <-time.After(time.Second):
+		t.Fatal("not accepted after release")
+	}
+}
+
+func TestListenTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := writeCert(t, dir)
+
+	lis, err := HttpConfig{TLS: &files}.listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	go func() {
+		conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+
+	conn, err := lis.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	tc, ok := conn.(*tls.Conn)
+	if !ok {
+		t.Fatalf("expected tls connection, got %T", conn)
+	}
+	if err = tc.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeCert(t *testing.T, dir string) certs.Files {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := certs.Files{Cert: filepath.Join(dir, "cert.pem"), Key: filepath.Join(dir, "key.pem")}
+	if err = ioutil.WriteFile(files.Cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(files.Key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
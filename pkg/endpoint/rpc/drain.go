@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+)
+
+// States of servers created by NewGrpcServer
+var servers sync.Map
+
+type serverState struct {
+	health *health.Server
+	active int64
+}
+
+func (s *serverState) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt64(&s.active, 1)
+		defer atomic.AddInt64(&s.active, -1)
+		return handler(ctx, req)
+	}
+}
+
+func (s *serverState) streamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		atomic.AddInt64(&s.active, 1)
+		defer atomic.AddInt64(&s.active, -1)
+		return handler(srv, ss)
+	}
+}
+
+// SetNotServing reports NOT_SERVING for all services, no-op if health server disabled
+func SetNotServing(s *grpc.Server) {
+	if v, ok := servers.Load(s); ok && v.(*serverState).health != nil {
+		v.(*serverState).health.Shutdown()
+	}
+}
+
+// ActiveCalls returns number of unary and stream calls in flight
+func ActiveCalls(s *grpc.Server) int64 {
+	if v, ok := servers.Load(s); ok {
+		return atomic.LoadInt64(&v.(*serverState).active)
+	}
+	return 0
+}
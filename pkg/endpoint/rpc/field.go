@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var errNoField = xerrors.New("no such field")
+
+// findField matches proto name or JSON name
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); fd.JSONName() == name {
+			return fd
+		}
+	}
+	return nil
+}
+
+// setField sets value of field path like "a.b.c", repeated field appends
+func setField(msg protoreflect.Message, path, value string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := findField(msg.Descriptor(), name)
+		if fd == nil || fd.IsMap() {
+			return xerrors.Errorf("%s: %w", path, errNoField)
+		}
+		if i < len(names)-1 {
+			if fd.Kind() != protoreflect.MessageKind || fd.IsList() {
+				return xerrors.Errorf("%s: %w", path, errNoField)
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+
+		v, err := parseValue(msg, fd, value)
+		if err != nil {
+			return xerrors.Errorf("%s: %w", path, err)
+		}
+		if fd.IsList() {
+			msg.Mutable(fd).List().Append(v)
+		} else {
+			msg.Set(fd, v)
+		}
+	}
+	return nil
+}
+
+func parseValue(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(value)), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	case protoreflect.MessageKind:
+		// Well-known types like Timestamp, decoded from quoted JSON string
+		var m protoreflect.Message
+		if fd.IsList() {
+			m = msg.Mutable(fd).List().NewElement().Message()
+		} else {
+			m = msg.NewField(fd).Message()
+		}
+		err := unmarshaler.Unmarshal([]byte(strconv.Quote(value)), m.Interface())
+		return protoreflect.ValueOfMessage(m), err
+	default:
+		return protoreflect.Value{}, xerrors.Errorf("unsupported kind: %s", fd.Kind())
+	}
+}
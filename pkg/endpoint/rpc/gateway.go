@@ -0,0 +1,280 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/plugin/ocgrpc"
+	"golang.org/x/xerrors"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+// Gateway serves unary methods of server as HTTP/JSON, grpc-gateway style.
+// Routes come from google.api.http annotations, default "POST /package.Service/Method" with body "*".
+// Calls go through an in-memory connection, so server interceptors apply.
+type Gateway struct {
+	listener *bufconn.Listener
+	conn     *grpc.ClientConn
+	routes   []route
+}
+
+type route struct {
+	verb         string
+	template     *template
+	body         string
+	responseBody string
+	fullMethod   string
+	input        protoreflect.MessageType
+	output       protoreflect.MessageType
+}
+
+var (
+	unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+	marshaler   = protojson.MarshalOptions{}
+)
+
+// NewGateway registers routes of server, call it after all services registered.
+// Dials insecure by default, pass credentials by opts if server uses TLS.
+func NewGateway(server *grpc.Server, opts ...grpc.DialOption) (*Gateway, error) {
+	g := &Gateway{listener: bufconn.Listen(1024 * 1024)}
+	for name, info := range server.GetServiceInfo() {
+		for _, m := range info.Methods {
+			if m.IsClientStream || m.IsServerStream {
+				continue
+			}
+			routes, err := newRoutes(name, m.Name)
+			if err != nil {
+				return nil, err
+			}
+			g.routes = append(g.routes, routes...)
+		}
+	}
+
+	go func() {
+		if err := server.Serve(g.listener); err != nil {
+			logrus.WithError(err).Warn("Gateway listener closed")
+		}
+	}()
+
+	if len(opts) == 0 {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	opts = append(opts,
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return g.listener.Dial() }),
+		grpc.WithStatsHandler(&ocgrpc.ClientHandler{}))
+
+	conn, err := grpc.Dial("bufnet", opts...)
+	if err != nil {
+		_ = g.listener.Close()
+		return nil, err
+	}
+	g.conn = conn
+	return g, nil
+}
+
+func (g *Gateway) Close() error {
+	_ = g.conn.Close()
+	return g.listener.Close()
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op errors.Op = "gateway.ServeHTTP"
+
+	// Forwarded to server by metadata, so both sides report the same one
+	requestId := tracing.NewRequestId(r.Context(), r.Header.Get(tracing.HeaderRequestId))
+	r = r.WithContext(tracing.WithRequestId(r.Context(), requestId))
+	r.Header.Set(tracing.HeaderRequestId, requestId)
+	w.Header().Set(tracing.HeaderRequestId, requestId)
+
+	path := r.URL.EscapedPath()
+	matched := false
+	for _, rt := range g.routes {
+		values, ok := rt.template.match(path)
+		if !ok {
+			continue
+		}
+		if matched = true; rt.verb != r.Method {
+			continue
+		}
+		if err := g.handle(w, r, rt, values); err != nil {
+			writeError(w, r, errors.E(op, err))
+		}
+		return
+	}
+
+	if matched {
+		writeError(w, r, errors.E(op, errors.Unimplemented, xerrors.Errorf("method not allowed: %s", r.Method)))
+	} else {
+		writeError(w, r, errors.E(op, errors.NotFound, xerrors.Errorf("not found: %s", path)))
+	}
+}
+
+func (g *Gateway) handle(w http.ResponseWriter, r *http.Request, rt route, values map[string]string) error {
+	req := rt.input.New()
+	if err := decodeBody(r, req, rt.body); err != nil {
+		return errors.E(errors.InvalidArgument, err)
+	}
+	for field, value := range values {
+		if err := setField(req, field, value); err != nil {
+			return errors.E(errors.InvalidArgument, err)
+		}
+	}
+	if rt.body != "*" {
+		for key, arr := range r.URL.Query() {
+			// Unknown query params are ignored
+			for _, value := range arr {
+				if err := setField(req, key, value); err != nil && !xerrors.Is(err, errNoField) {
+					return errors.E(errors.InvalidArgument, err)
+				}
+			}
+		}
+	}
+
+	resp := rt.output.New()
+	ctx := metadata.NewOutgoingContext(r.Context(), outgoingMetadata(r.Header))
+	if err := g.conn.Invoke(ctx, rt.fullMethod, req.Interface(), resp.Interface()); err != nil {
+		// Keeps code of status for errors.Http
+		return errors.FromGrpc(ctx, err)
+	}
+
+	var out proto.Message = resp.Interface()
+	if rt.responseBody != "" {
+		fd := resp.Descriptor().Fields().ByName(protoreflect.Name(rt.responseBody))
+		if fd == nil || fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return errors.E(errors.Internal, xerrors.Errorf("invalid response body: %q", rt.responseBody))
+		}
+		out = resp.Get(fd).Message().Interface()
+	}
+
+	data, err := marshaler.Marshal(out)
+	if err != nil {
+		return errors.E(errors.Internal, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	return err
+}
+
+func newRoutes(service, method string) ([]route, error) {
+	fullName := protoreflect.FullName(service + "." + method)
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(fullName)
+	if err != nil {
+		return nil, xerrors.Errorf("descriptor of %s: %w", fullName, err)
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, xerrors.Errorf("not a method: %s", fullName)
+	}
+
+	base := route{fullMethod: "/" + service + "/" + method}
+	if base.input, err = protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName()); err != nil {
+		return nil, err
+	}
+	if base.output, err = protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName()); err != nil {
+		return nil, err
+	}
+
+	rule, _ := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if rule == nil {
+		rule = &annotations.HttpRule{
+			Pattern: &annotations.HttpRule_Post{Post: base.fullMethod},
+			Body:    "*",
+		}
+	}
+
+	var routes []route
+	for _, r := range append([]*annotations.HttpRule{rule}, rule.AdditionalBindings...) {
+		rt := base
+		var path string
+		switch p := r.Pattern.(type) {
+		case *annotations.HttpRule_Get:
+			rt.verb, path = http.MethodGet, p.Get
+		case *annotations.HttpRule_Put:
+			rt.verb, path = http.MethodPut, p.Put
+		case *annotations.HttpRule_Post:
+			rt.verb, path = http.MethodPost, p.Post
+		case *annotations.HttpRule_Delete:
+			rt.verb, path = http.MethodDelete, p.Delete
+		case *annotations.HttpRule_Patch:
+			rt.verb, path = http.MethodPatch, p.Patch
+		case *annotations.HttpRule_Custom:
+			rt.verb, path = p.Custom.Kind, p.Custom.Path
+		default:
+			return nil, xerrors.Errorf("unsupported http rule of %s", fullName)
+		}
+		if rt.template, err = parseTemplate(path); err != nil {
+			return nil, err
+		}
+		rt.body, rt.responseBody = r.Body, r.ResponseBody
+		routes = append(routes, rt)
+	}
+	return routes, nil
+}
+
+func decodeBody(r *http.Request, msg protoreflect.Message, body string) error {
+	if body == "" {
+		return nil
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil || len(data) == 0 {
+		return err
+	}
+	if body == "*" {
+		return unmarshaler.Unmarshal(data, msg.Interface())
+	}
+
+	fd := findField(msg.Descriptor(), body)
+	if fd == nil {
+		return xerrors.Errorf("invalid body field: %q", body)
+	}
+	if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() {
+		return unmarshaler.Unmarshal(data, msg.Mutable(fd).Message().Interface())
+	}
+
+	// Scalar or repeated field, decode via a wrapping object
+	wrapped, err := json.Marshal(map[string]json.RawMessage{string(fd.Name()): data})
+	if err != nil {
+		return err
+	}
+	return unmarshaler.Unmarshal(wrapped, msg.Interface())
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := errors.Http(tracing.RequestId(r.Context()), err)
+	data, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.Error.Code)
+	_, _ = w.Write(data)
+}
+
+func outgoingMetadata(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range header {
+		key = strings.ToLower(key)
+		if strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		switch key {
+		case "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade", "te",
+			"content-type", "content-length", "host":
+			continue
+		}
+		md[key] = append(md[key], values...)
+	}
+	return md
+}
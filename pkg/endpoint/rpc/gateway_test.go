@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestTemplateMatch(t *testing.T) {
+	testCases := []struct {
+		template string
+		path     string
+		expected map[string]string
+	}{
+		{"/v1/books/{id}", "/v1/books/1", map[string]string{"id": "1"}},
+		{"/v1/books/{id}", "/v1/books/1/2", nil},
+		{"/v1/{name=shelves/*}/books", "/v1/shelves/a/books", map[string]string{"name": "shelves/a"}},
+		{"/v1/{name=files/**}", "/v1/files/a/b%2Fc", map[string]string{"name": "files/a/b/c"}},
+		{"/v1/books/{id}:publish", "/v1/books/1:publish", map[string]string{"id": "1"}},
+		{"/v1/books/{id}:publish", "/v1/books/1", nil},
+		{"/pkg.Service/Method", "/pkg.Service/Method", map[string]string{}},
+	}
+
+	for _, c := range testCases {
+		tpl, err := parseTemplate(c.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values, ok := tpl.match(c.path)
+		if ok != (c.expected != nil) || (ok && !reflect.DeepEqual(values, c.expected)) {
+			t.Fatalf("%s matches %s: expected %v got %v", c.template, c.path, c.expected, values)
+		}
+	}
+}
+
+func TestGateway(t *testing.T) {
+	s := grpc.NewServer()
+	registerHealthServer(s)
+	defer s.Stop()
+
+	g, err := NewGateway(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	testCases := []struct {
+		method string
+		path   string
+		req    string
+		code   int
+		body   string
+	}{
+		{http.MethodPost, "/grpc.health.v1.Health/Check", "{}", http.StatusOK, `"SERVING"`},
+		{http.MethodGet, "/grpc.health.v1.Health/Check", "{}", http.StatusNotImplemented, `"UNIMPLEMENTED"`},
+		{http.MethodPost, "/not/found", "{}", http.StatusNotFound, `"NOT_FOUND"`},
+		// Status error of backend
+		{http.MethodPost, "/grpc.health.v1.Health/Check", `{"service": "missing"}`, http.StatusNotFound, `"NOT_FOUND"`},
+	}
+
+	for _, c := range testCases {
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, httptest.NewRequest(c.method, c.path, strings.NewReader(c.req)))
+		if w.Code != c.code || !strings.Contains(w.Body.String(), c.body) {
+			t.Fatalf("%s %s: expected %d %s, got %d %s", c.method, c.path, c.code, c.body, w.Code, w.Body)
+		}
+	}
+}
@@ -10,12 +10,18 @@ import (
 	grpcPrometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/GotaX/go-server-skeleton/pkg/ext/auth"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/certs"
 	grpc2 "github.com/GotaX/go-server-skeleton/pkg/ext/grpc"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/idempotency"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/validation"
 )
 
 func init() {
@@ -26,17 +32,48 @@ type Service interface {
 	Register(server *grpc.Server)
 }
 
-func registerHealthServer(s *grpc.Server) {
+func registerHealthServer(s *grpc.Server) *health.Server {
 	hsrv := health.NewServer()
 	hsrv.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
 	healthpb.RegisterHealthServer(s, hsrv)
+	return hsrv
 }
 
 type GrpcConfiguration struct {
 	LogEntry     *logrus.Entry
 	LogExtractor grpcCtxTags.RequestFieldExtractorFunc
 	LogDecider   func(fullMethodName string, err error) bool
-	services     []Service
+
+	// Extra interceptors, placed before the built-in ones
+	PreUnaryInterceptors  []grpc.UnaryServerInterceptor
+	PreStreamInterceptors []grpc.StreamServerInterceptor
+	// Extra interceptors, placed after the built-in ones
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+
+	// Authenticate calls if set, placed after the built-in interceptors
+	Auth *auth.Auth
+	// Replays unary calls with "idempotency-key" metadata if set, placed after auth and validation
+	Idempotency *idempotency.Idempotency
+
+	// TLS or mTLS with hot reload, plain text if nil
+	TLS *certs.Files
+
+	MaxRecvMsgSize       int
+	MaxSendMsgSize       int
+	MaxConcurrentStreams uint32
+
+	// Override default keepalive settings if not nil
+	KeepaliveEnforcement *keepalive.EnforcementPolicy
+	KeepaliveParams      *keepalive.ServerParameters
+
+	DisableReflection bool
+	DisableHealth     bool
+
+	// Extra server options, applied last
+	ServerOptions []grpc.ServerOption
+
+	services []Service
 }
 
 func (c *GrpcConfiguration) Register(service Service) {
@@ -53,42 +90,97 @@ func NewGrpcServer(configure func(c *GrpcConfiguration)) *grpc.Server {
 	configure(c)
 
 	// Ref: https://github.com/grpc/grpc-go/blob/master/examples/features/keepalive/server/main.go
-	kaep := keepalive.EnforcementPolicy{
+	kaep := &keepalive.EnforcementPolicy{
 		MinTime:             5 * time.Second, // If a client pings more than once every 5 seconds, terminate the connection
 		PermitWithoutStream: true,            // Allow pings even when there are no active streams
 	}
 
-	kasp := keepalive.ServerParameters{
+	kasp := &keepalive.ServerParameters{
 		MaxConnectionIdle:     15 * time.Second, // If a client is idle for 15 seconds, send a GOAWAY
 		MaxConnectionAgeGrace: 5 * time.Second,  // Allow 5 seconds for pending RPCs to complete before forcibly closing connections
 		Time:                  5 * time.Second,  // Ping the client if it is idle for 5 seconds to ensure the connection is still active
 		Timeout:               3 * time.Second,  // Wait 3 seconds for the ping ack before assuming the connection is dead
 	}
 
-	s := grpc.NewServer(
+	if c.KeepaliveEnforcement != nil {
+		kaep = c.KeepaliveEnforcement
+	}
+	if c.KeepaliveParams != nil {
+		kasp = c.KeepaliveParams
+	}
+
+	state := &serverState{}
+	streamInterceptors := []grpc.StreamServerInterceptor{state.streamInterceptor()}
+	streamInterceptors = append(streamInterceptors, c.PreStreamInterceptors...)
+	streamInterceptors = append(streamInterceptors,
+		grpcCtxTags.StreamServerInterceptor(grpcCtxTags.WithFieldExtractor(c.LogExtractor)),
+		propagation.StreamServerInterceptor(),
+		grpc2.StreamServerRequestId(),
+		grpcLogrus.StreamServerInterceptor(c.LogEntry, grpc2.LogDecider()),
+		grpcPrometheus.StreamServerInterceptor,
+		grpcRecovery.StreamServerInterceptor(grpc2.RecoveryHandler()),
+		grpc2.StreamServerErrorHandler())
+	if c.Auth != nil {
+		streamInterceptors = append(streamInterceptors, c.Auth.StreamServerInterceptor())
+	}
+	streamInterceptors = append(streamInterceptors, validation.StreamServerInterceptor())
+	streamInterceptors = append(streamInterceptors, c.StreamInterceptors...)
+
+	unaryInterceptors := []grpc.UnaryServerInterceptor{state.unaryInterceptor()}
+	unaryInterceptors = append(unaryInterceptors, c.PreUnaryInterceptors...)
+	unaryInterceptors = append(unaryInterceptors,
+		grpcCtxTags.UnaryServerInterceptor(grpcCtxTags.WithFieldExtractor(c.LogExtractor)),
+		propagation.UnaryServerInterceptor(),
+		grpc2.UnaryServerRequestId(),
+		grpcLogrus.UnaryServerInterceptor(c.LogEntry, grpc2.LogDecider()),
+		grpcPrometheus.UnaryServerInterceptor,
+		grpcRecovery.UnaryServerInterceptor(grpc2.RecoveryHandler()),
+		grpc2.UnaryServerErrorHandler())
+	if c.Auth != nil {
+		unaryInterceptors = append(unaryInterceptors, c.Auth.UnaryServerInterceptor())
+	}
+	unaryInterceptors = append(unaryInterceptors, validation.UnaryServerInterceptor())
+	if c.Idempotency != nil {
+		unaryInterceptors = append(unaryInterceptors, c.Idempotency.UnaryServerInterceptor())
+	}
+	unaryInterceptors = append(unaryInterceptors, c.UnaryInterceptors...)
+
+	opts := []grpc.ServerOption{
 		grpc.StatsHandler(grpc2.TraceHandler()),
-		grpc.StreamInterceptor(grpcMiddleware.ChainStreamServer(
-			grpcCtxTags.StreamServerInterceptor(grpcCtxTags.WithFieldExtractor(c.LogExtractor)),
-			grpcLogrus.StreamServerInterceptor(c.LogEntry, grpc2.LogDecider()),
-			grpcPrometheus.StreamServerInterceptor,
-			grpcRecovery.StreamServerInterceptor(grpc2.RecoveryHandler()),
-			grpc2.StreamServerErrorHandler(),
-		)),
-		grpc.UnaryInterceptor(grpcMiddleware.ChainUnaryServer(
-			grpcCtxTags.UnaryServerInterceptor(grpcCtxTags.WithFieldExtractor(c.LogExtractor)),
-			grpcLogrus.UnaryServerInterceptor(c.LogEntry, grpc2.LogDecider()),
-			grpcPrometheus.UnaryServerInterceptor,
-			grpcRecovery.UnaryServerInterceptor(grpc2.RecoveryHandler()),
-			grpc2.UnaryServerErrorHandler(),
-		)),
-		grpc.KeepaliveEnforcementPolicy(kaep),
-		grpc.KeepaliveParams(kasp))
+		grpc.StreamInterceptor(grpcMiddleware.ChainStreamServer(streamInterceptors...)),
+		grpc.UnaryInterceptor(grpcMiddleware.ChainUnaryServer(unaryInterceptors...)),
+		grpc.KeepaliveEnforcementPolicy(*kaep),
+		grpc.KeepaliveParams(*kasp),
+	}
+	if c.TLS != nil {
+		tc, err := c.TLS.ServerConfig()
+		if err != nil {
+			logrus.WithError(err).Fatal("Fail to load certificates")
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tc)))
+	}
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+	if c.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(c.MaxConcurrentStreams))
+	}
+
+	s := grpc.NewServer(append(opts, c.ServerOptions...)...)
 
 	for _, srv := range c.services {
 		srv.Register(s)
 	}
 
-	registerHealthServer(s)
-	reflection.Register(s)
+	if !c.DisableHealth {
+		state.health = registerHealthServer(s)
+	}
+	servers.Store(s, state)
+	if !c.DisableReflection {
+		reflection.Register(s)
+	}
 	return s
 }
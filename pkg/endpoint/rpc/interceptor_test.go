@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/GotaX/go-server-skeleton/pkg/ext/idempotency"
+)
+
+type memStore map[string]idempotency.Record
+
+func (s memStore) Acquire(_ context.Context, key string, rec idempotency.Record, _ time.Duration) (*idempotency.Record, error) {
+	if existing, ok := s[key]; ok {
+		return &existing, nil
+	}
+	s[key] = rec
+	return nil, nil
+}
+
+func (s memStore) Save(_ context.Context, key string, rec idempotency.Record, _ time.Duration) error {
+	s[key] = rec
+	return nil
+}
+
+func (s memStore) Release(_ context.Context, key string) error {
+	delete(s, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	store := memStore{}
+	s := NewGrpcServer(func(c *GrpcConfiguration) {
+		c.Idempotency = idempotency.New(idempotency.Options{Store: store})
+	})
+	defer s.Stop()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = s.Serve(lis) }()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), idempotency.MetadataKey, "k1")
+	for i := 0; i < 2; i++ {
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("unexpected status: %v", resp.Status)
+		}
+	}
+
+	rec, ok := store["/grpc.health.v1.Health/Check:k1"]
+	if !ok || !rec.Done {
+		t.Fatalf("expected completed record, got %+v", store)
+	}
+}
@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// template of google.api.http path, e.g. "/v1/{name=shelves/*}/books/{book_id}:publish"
+type template struct {
+	// Literal, "*" or "**"
+	segments []string
+	verb     string
+	vars     []variable
+}
+
+type variable struct {
+	field      string
+	start, end int
+}
+
+func parseTemplate(path string) (*template, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, xerrors.Errorf("invalid template: %q", path)
+	}
+
+	t := &template{}
+	rest := path[1:]
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.ContainsAny(rest[i:], "/}") {
+		rest, t.verb = rest[:i], rest[i+1:]
+	}
+
+	for rest != "" {
+		var seg string
+		if strings.HasPrefix(rest, "{") {
+			end := strings.Index(rest, "}")
+			if end < 0 {
+				return nil, xerrors.Errorf("invalid template: %q", path)
+			}
+			seg, rest = rest[1:end], strings.TrimPrefix(rest[end+1:], "/")
+
+			v := variable{field: seg, start: len(t.segments)}
+			pattern := "*"
+			if i := strings.Index(seg, "="); i >= 0 {
+				v.field, pattern = seg[:i], seg[i+1:]
+			}
+			t.segments = append(t.segments, strings.Split(pattern, "/")...)
+			v.end = len(t.segments)
+			t.vars = append(t.vars, v)
+			continue
+		}
+
+		if i := strings.Index(rest, "/"); i >= 0 {
+			seg, rest = rest[:i], rest[i+1:]
+		} else {
+			seg, rest = rest, ""
+		}
+		t.segments = append(t.segments, seg)
+	}
+
+	for i, seg := range t.segments {
+		if seg == "**" && i != len(t.segments)-1 {
+			return nil, xerrors.Errorf("'**' must be the last segment: %q", path)
+		}
+	}
+	return t, nil
+}
+
+// match returns values of variables if escaped path matches
+func (t *template) match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	path = path[1:]
+	if t.verb != "" {
+		if !strings.HasSuffix(path, ":"+t.verb) {
+			return nil, false
+		}
+		path = strings.TrimSuffix(path, ":"+t.verb)
+	}
+
+	var segments []string
+	if path != "" {
+		segments = strings.Split(path, "/")
+	}
+
+	// Index of path segment where each template segment ends
+	ends := make([]int, len(t.segments)+1)
+	for i, seg := range t.segments {
+		switch {
+		case seg == "**":
+			ends[i+1] = len(segments)
+		case i >= len(segments):
+			return nil, false
+		case seg == "*":
+			ends[i+1] = i + 1
+		default:
+			if unescaped, err := url.PathUnescape(segments[i]); err != nil || unescaped != seg {
+				return nil, false
+			}
+			ends[i+1] = i + 1
+		}
+	}
+	if ends[len(t.segments)] != len(segments) {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(t.vars))
+	for _, v := range t.vars {
+		parts := segments[ends[v.start]:ends[v.end]]
+		for i := range parts {
+			unescaped, err := url.PathUnescape(parts[i])
+			if err != nil {
+				return nil, false
+			}
+			parts[i] = unescaped
+		}
+		values[v.field] = strings.Join(parts, "/")
+	}
+	return values, true
+}
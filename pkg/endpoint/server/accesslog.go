@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+)
+
+// Mask of sensitive values
+const redacted = "***"
+
+type AccessLogConfiguration struct {
+	// Bodies larger than it are not logged, 0 disables body capture
+	MaxBodySize int
+	// Media types of bodies to capture, only JSON and form bodies can be redacted
+	ContentTypes []string
+	// Values of body fields and query params are masked if name contains any of them, case-insensitive
+	Redact []string
+	// Ratio of successful requests to log, failed ones are always logged
+	SampleRate float64
+}
+
+type accessLogger struct {
+	AccessLogConfiguration
+}
+
+func newAccessLogger(c AccessLogConfiguration) *accessLogger {
+	redact := make([]string, len(c.Redact))
+	for i, name := range c.Redact {
+		redact[i] = strings.ToLower(name)
+	}
+	c.Redact = redact
+	return &accessLogger{c}
+}
+
+// capture reports whether body of contentType should be logged
+func (l *accessLogger) capture(contentType string) bool {
+	if l.MaxBodySize <= 0 || contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range l.ContentTypes {
+		if strings.EqualFold(t, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *accessLogger) sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, r := range l.Redact {
+		if strings.Contains(name, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// setBody puts redacted body into fields, truncated ones are omitted since they can't be redacted reliably
+func (l *accessLogger) setBody(fields map[string]interface{}, contentType string, data []byte, truncated bool) {
+	if truncated || len(data) > l.MaxBodySize {
+		fields[lfBodyTruncated] = true
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		var v interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			// Malformed, may leak anything
+			fields[lfBody] = redacted
+			return
+		}
+		if out, err := json.Marshal(l.redactJson(v)); err == nil {
+			fields[lfBody] = string(out)
+		}
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			fields[lfBody] = redacted
+			return
+		}
+		fields[lfBody] = l.redactValues(values).Encode()
+	default:
+		fields[lfBody] = string(data)
+	}
+}
+
+func (l *accessLogger) redactJson(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if l.sensitive(key) {
+				v[key] = redacted
+			} else {
+				v[key] = l.redactJson(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = l.redactJson(value)
+		}
+	}
+	return v
+}
+
+func (l *accessLogger) redactValues(values url.Values) url.Values {
+	for key := range values {
+		if l.sensitive(key) {
+			values[key] = []string{redacted}
+		}
+	}
+	return values
+}
+
+// redactUri masks sensitive query params of uri, e.g. "/login?token=***"
+func (l *accessLogger) redactUri(path, rawQuery string) string {
+	if rawQuery == "" {
+		return path
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return path + "?" + redacted
+	}
+	for key := range values {
+		if l.sensitive(key) {
+			return path + "?" + l.redactValues(values).Encode()
+		}
+	}
+	return path + "?" + rawQuery
+}
+
+// log writes successful requests at info level by sampling, others at warn level
+func (l *accessLogger) log(fields map[string]interface{}, message string) {
+	code, _ := fields[lfCode].(int)
+	if code < http.StatusBadRequest {
+		if l.SampleRate < 1 && rand.Float64() >= l.SampleRate {
+			return
+		}
+		logrus.WithFields(fields).Info(message)
+	} else {
+		logrus.WithFields(fields).Warn(message)
+	}
+}
+
+func traceId(ctx context.Context) string {
+	if span := trace.FromContext(ctx); span != nil {
+		return span.SpanContext().TraceID.String()
+	}
+	return ""
+}
+
+// cappedBuffer keeps at most limit bytes, but counts all written
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+	n     int64
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.n += int64(len(p))
+	if remain := b.limit - b.Len(); remain > 0 {
+		if len(p) > remain {
+			_, _ = b.Buffer.Write(p[:remain])
+		} else {
+			_, _ = b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) truncated() bool {
+	return b.n > int64(b.Len())
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestAccessLogBody(t *testing.T) {
+	l := newAccessLogger(newConfiguration(nil).AccessLogConfiguration)
+
+	testCases := []struct {
+		contentType string
+		body        string
+		expected    interface{}
+	}{
+		{"application/json", `{"name":"a","Password":"p","items":[{"access_token":"t","n":1.50}]}`,
+			`{"Password":"***","items":[{"access_token":"***","n":1.50}],"name":"a"}`},
+		{"application/json; charset=utf-8", `{"name":`, redacted},
+		{"application/x-www-form-urlencoded", "name=a&password=p", "name=a&password=%2A%2A%2A"},
+		{"application/json", `"` + strings.Repeat("a", l.MaxBodySize) + `"`, nil},
+	}
+	for _, c := range testCases {
+		fields := map[string]interface{}{}
+		l.setBody(fields, c.contentType, []byte(c.body), false)
+		if actual := fields[lfBody]; actual != c.expected {
+			t.Fatalf("%s: expected %v got %v", c.body, c.expected, actual)
+		}
+	}
+
+	if expected, actual := "/login?name=a&token=%2A%2A%2A", l.redactUri("/login", "token=t&name=a"); actual != expected {
+		t.Fatalf("expected %s got %s", expected, actual)
+	}
+	if l.capture("text/html") || !l.capture("application/json; charset=utf-8") {
+		t.Fatal("unexpected capture of content type")
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+
+	handler := Gin(func(r gin.IRouter) {
+		r.POST("/ok", func(ctx *gin.Context) { ctx.String(http.StatusOK, "ok") })
+	}, func(c *Configuration) {
+		c.SampleRate = 0
+		c.MaxBodySize = 8
+	})
+
+	for _, path := range []string{"/ok", "/nope"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"password":"p"}`))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var entries []*logrus.Entry
+	for _, e := range hook.AllEntries() {
+		if _, ok := e.Data[lfLatency]; ok {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) != 1 || entries[0].Data[lfPath] != "/nope" {
+		t.Fatalf("expected only failed request logged, got %v", entries)
+	}
+	if _, ok := entries[0].Data[lfBody]; ok {
+		t.Fatalf("unexpected body: %v", entries[0].Data[lfBody])
+	}
+}
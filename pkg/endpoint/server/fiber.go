@@ -2,69 +2,104 @@ package server
 
 import (
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	mLogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/gofiber/fiber/v2/middleware/requestid"
-	"github.com/rs/xid"
-	"github.com/sirupsen/logrus"
 
 	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
 )
 
-func Fiber(config func(r *fiber.App)) *fiber.App {
+func Fiber(config func(r *fiber.App), configs ...func(c *Configuration)) *fiber.App {
+	c := newConfiguration(configs)
 	app := fiber.New(fiber.Config{ErrorHandler: handleError})
 
-	app.Use(requestid.New(requestid.Config{ContextKey: keyReqId}))
+	app.Use(tracing.Fiber())
+	app.Use(propagation.Fiber())
+	app.Use(fiberRequestId())
 	app.Use(mLogger.New())
-	app.Use(handleAccessLog())
+	app.Use(handleAccessLog(newAccessLogger(c.AccessLogConfiguration)))
+	app.Use(fiberMetrics())
 	app.Use(recover.New())
+	if c.Auth != nil {
+		app.Use(c.Auth.Fiber())
+	}
 
 	config(app)
 	return app
 }
 
+// fiberRequestId honours incoming X-Request-Id, otherwise derives one from trace, same as Gin
+func fiberRequestId() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		requestId := tracing.NewRequestId(ctx.Context(), ctx.Get(tracing.HeaderRequestId))
+		ctx.Locals(keyReqId, requestId)
+		propagation.FiberWith(ctx, tracing.HeaderRequestId, requestId)
+		ctx.Set(tracing.HeaderRequestId, requestId)
+		return ctx.Next()
+	}
+}
+
 func handleError(ctx *fiber.Ctx, err error) error {
-	requestId := xid.New().String()
-	response := errors.Http(requestId, err)
+	requestId, _ := ctx.Locals(keyReqId).(string)
+	response := errors.Http(requestId, fiberError(err))
 	return ctx.Status(response.Error.Code).JSON(response)
 }
 
-func handleAccessLog() fiber.Handler {
+// fiberError keeps status of errors by fiber, e.g. 404 of unmatched routes, which is UNKNOWN otherwise
+func fiberError(err error) error {
+	if e, ok := err.(*fiber.Error); ok {
+		return errors.E(errors.StrToCode(httpStatus(e.Code)), err)
+	}
+	return err
+}
+
+func handleAccessLog(l *accessLogger) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		st := time.Now()
 
 		err := ctx.Next()
 
+		latency := time.Since(st)
+		// Copied since fasthttp reuses buffers
+		method := string([]byte(ctx.Method()))
+		uri := l.redactUri(string(ctx.Request().URI().Path()), string(ctx.Request().URI().QueryString()))
+		route := fiberRoute(ctx, err)
+
 		fields := map[string]interface{}{
-			lfReqId:  ctx.Locals(keyReqId),
-			lfStatus: errors.CodeToStr(errors.OK),
-			lfCode:   fiber.StatusOK,
-			lfError:  "",
+			lfReqId:    ctx.Locals(keyReqId),
+			lfStatus:   errors.CodeToStr(errors.OK),
+			lfCode:     fiber.StatusOK,
+			lfError:    "",
+			lfMethod:   method,
+			lfPath:     uri,
+			lfRoute:    route,
+			lfIp:       string([]byte(ctx.IP())),
+			lfUa:       string(ctx.Request().Header.UserAgent()),
+			lfTraceId:  traceId(tracing.FiberContext(ctx)),
+			lfLatency:  latency.Milliseconds(),
+			lfBytesIn:  len(ctx.Body()),
+			lfBytesOut: len(ctx.Response().Body()),
 		}
 
 		if err != nil {
-			code := errors.Code(err)
-			fields[lfCode] = errors.CodeToHttp(code)
-			fields[lfStatus] = errors.CodeToStr(code)
-			fields[lfError] = err.Error()
+			e := errors.Http("", fiberError(err)).Error
+			fields[lfCode], fields[lfStatus], fields[lfError] = e.Code, e.Status, err.Error()
+		} else if code := ctx.Response().StatusCode(); code != fiber.StatusOK {
+			fields[lfCode], fields[lfStatus] = code, httpStatus(code)
 		}
 
-		message := fmt.Sprintf("[%v] %v(%v) - %v %v - %v",
-			time.Since(st).Truncate(time.Millisecond),
-			fields[lfStatus], fields[lfCode],
-			ctx.Method(), ctx.OriginalURL(),
-			string(ctx.Body()))
-
-		logger := logrus.WithFields(fields)
-		if fields[lfCode] == http.StatusOK {
-			logger.Info(message)
-		} else {
-			logger.Warn(message)
+		if contentType := string(ctx.Request().Header.ContentType()); l.capture(contentType) {
+			l.setBody(fields, contentType, ctx.Body(), false)
 		}
+
+		l.log(fields, fmt.Sprintf("[%v] %v(%v) - %v %v",
+			latency.Truncate(time.Millisecond),
+			fields[lfStatus], fields[lfCode],
+			method, uri))
 		return err
 	}
 }
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+func TestRequestIdPropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := Gin(func(r gin.IRouter) {
+		r.GET("/", func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, propagation.FromContext(ctx.Request.Context())["x-request-id"])
+		})
+	})
+	app := Fiber(func(r *fiber.App) {
+		r.Get("/", func(ctx *fiber.Ctx) error {
+			return ctx.SendString(propagation.FromContext(ctx.Context())["x-request-id"])
+		})
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if id := w.Header().Get(tracing.HeaderRequestId); id == "" || w.Body.String() != id {
+		t.Fatalf("gin: expected generated id %q propagated, got %q", id, w.Body)
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if id := resp.Header.Get(tracing.HeaderRequestId); id == "" || string(body) != id {
+		t.Fatalf("fiber: expected generated id %q propagated, got %q", id, body)
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	. "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+// Route label of requests matching no route
+const routeUnmatched = "unmatched"
+
+var (
+	httpRequests = NewCounterVec(CounterOpts{
+		Name: "http_server_requests_total",
+		Help: "HTTP 服务端累计处理请求数",
+	}, []string{"method", "route", "code", "status"})
+	httpRequestSeconds = NewHistogramVec(HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "HTTP 服务端请求处理耗时",
+		Buckets: DefBuckets,
+	}, []string{"method", "route", "code", "status"})
+	httpInFlight = NewGaugeVec(GaugeOpts{
+		Name: "http_server_requests_in_flight",
+		Help: "HTTP 服务端正在处理的请求数",
+	}, []string{"method"})
+
+	registerHttpMetrics = &sync.Once{}
+)
+
+func registerMetrics() {
+	registerHttpMetrics.Do(func() { MustRegister(httpRequests, httpRequestSeconds, httpInFlight) })
+}
+
+// ginMetrics records requests by route template, place it after accessLog to see errors rendered by RenderError
+func ginMetrics() gin.HandlerFunc {
+	registerMetrics()
+
+	return func(ctx *gin.Context) {
+		method := ctx.Request.Method
+		inFlight := httpInFlight.With(Labels{"method": method})
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		st := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = routeUnmatched
+		}
+		code := ctx.Writer.Status()
+		status := httpStatus(code)
+		if s, ok := ctx.GetStringMap(lf)[lfStatus].(string); ok && s != errors.CodeToStr(errors.OK) {
+			status = s
+		}
+		observeHttp(method, route, code, status, st)
+	}
+}
+
+// fiberMetrics records requests by route template, errors are rendered by handleError later
+func fiberMetrics() fiber.Handler {
+	registerMetrics()
+
+	return func(ctx *fiber.Ctx) error {
+		// Copied since fasthttp reuses buffers
+		method := string([]byte(ctx.Method()))
+		inFlight := httpInFlight.With(Labels{"method": method})
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		st := time.Now()
+		err := ctx.Next()
+
+		route := fiberRoute(ctx, err)
+		code := ctx.Response().StatusCode()
+		status := httpStatus(code)
+		if err != nil {
+			// Same as rendered by handleError
+			e := errors.Http("", fiberError(err)).Error
+			code, status = e.Code, e.Status
+		}
+		observeHttp(method, route, code, status, st)
+		return err
+	}
+}
+
+// fiberRoute returns route template, or routeUnmatched if only middlewares passed
+func fiberRoute(ctx *fiber.Ctx, err error) string {
+	// Fiber reports "Cannot GET /path" once no route matches, route of middleware is left
+	if e, ok := err.(*fiber.Error); ok && e.Code == fiber.StatusNotFound && strings.HasPrefix(e.Message, "Cannot ") {
+		return routeUnmatched
+	}
+	if ctx.Route().Method == "USE" {
+		return routeUnmatched
+	}
+	return ctx.Route().Path
+}
+
+func observeHttp(method, route string, code int, status string, st time.Time) {
+	labels := Labels{"method": method, "route": route, "code": strconv.Itoa(code), "status": status}
+	httpRequests.With(labels).Inc()
+	httpRequestSeconds.With(labels).Observe(time.Since(st).Seconds())
+}
+
+// httpStatus maps status code of responses not rendered from errors
+func httpStatus(code int) string {
+	var c codes.Code
+	switch {
+	case code < http.StatusBadRequest:
+		c = errors.OK
+	case code == http.StatusBadRequest:
+		c = errors.InvalidArgument
+	case code == http.StatusUnauthorized:
+		c = errors.Unauthenticated
+	case code == http.StatusForbidden:
+		c = errors.PermissionDenied
+	case code == http.StatusNotFound:
+		c = errors.NotFound
+	case code == http.StatusConflict:
+		c = errors.Aborted
+	case code == http.StatusTooManyRequests:
+		c = errors.ResourceExhausted
+	case code == 499:
+		c = errors.Canceled
+	case code == http.StatusNotImplemented:
+		c = errors.Unimplemented
+	case code == http.StatusServiceUnavailable:
+		c = errors.Unavailable
+	case code == http.StatusGatewayTimeout:
+		c = errors.DeadlineExceeded
+	case code < http.StatusInternalServerError:
+		c = errors.FailedPrecondition
+	default:
+		c = errors.Internal
+	}
+	return errors.CodeToStr(c)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	. "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := Gin(func(r gin.IRouter) {
+		r.GET("/gin/:id", func(ctx *gin.Context) {
+			RenderError(ctx, "test", NotFound("user", ctx.Param("id")))
+		})
+	})
+	app := Fiber(func(r *fiber.App) {
+		r.Get("/fiber/:id", func(ctx *fiber.Ctx) error { return ctx.SendString("ok") })
+	})
+
+	for _, path := range []string{"/gin/1", "/gin/2", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	for _, path := range []string{"/fiber/1", "/nope"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path == "/nope" && resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 rendered, got %d", resp.StatusCode)
+		}
+	}
+
+	testCases := []struct {
+		labels   Labels
+		expected float64
+	}{
+		{Labels{"method": "GET", "route": "/gin/:id", "code": "404", "status": "NOT_FOUND"}, 2},
+		// "/nope" of both Gin and Fiber
+		{Labels{"method": "GET", "route": routeUnmatched, "code": "404", "status": "NOT_FOUND"}, 2},
+		{Labels{"method": "GET", "route": "/fiber/:id", "code": "200", "status": "OK"}, 1},
+	}
+	for _, c := range testCases {
+		if actual := testutil.ToFloat64(httpRequests.With(c.labels)); actual != c.expected {
+			t.Fatalf("%v: expected %v got %v", c.labels, c.expected, actual)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package server
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,58 +9,114 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 
 	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
 	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
 )
 
 const (
-	keyReqId = "server.request_id"
+	keyReqId = tracing.LocalsRequestId
 
 	lf       = "log.fields"
 	lfReqId  = "request_id"
 	lfCode   = "code"
 	lfStatus = "status"
 	lfError  = "error"
+
+	lfMethod        = "method"
+	lfPath          = "path"
+	lfRoute         = "route"
+	lfIp            = "ip"
+	lfUa            = "ua"
+	lfTraceId       = "trace_id"
+	lfLatency       = "latency_ms"
+	lfBytesIn       = "bytes_in"
+	lfBytesOut      = "bytes_out"
+	lfBody          = "body"
+	lfBodyTruncated = "body_truncated"
 )
 
-func Gin(router func(gin.IRouter)) http.Handler {
+// Configuration of Gin and Fiber servers
+type Configuration struct {
+	AccessLogConfiguration
+	// Authenticates requests after access log and metrics if set, e.g. *auth.Auth
+	Auth Authenticator
+}
+
+// Authenticator is implemented by *auth.Auth
+type Authenticator interface {
+	Gin() gin.HandlerFunc
+	Fiber() fiber.Handler
+}
+
+func newConfiguration(configs []func(c *Configuration)) Configuration {
+	c := Configuration{AccessLogConfiguration: AccessLogConfiguration{
+		MaxBodySize:  4 * 1024,
+		ContentTypes: []string{"application/json", "application/x-www-form-urlencoded"},
+		Redact:       []string{"password", "token", "secret"},
+		SampleRate:   1,
+	}}
+	for _, configure := range configs {
+		configure(&c)
+	}
+	return c
+}
+
+func Gin(router func(gin.IRouter), configs ...func(c *Configuration)) http.Handler {
+	c := newConfiguration(configs)
 	r := gin.New()
 	r.Use(gin.LoggerWithConfig(gin.LoggerConfig{
 		Output: logrus.StandardLogger().WriterLevel(logrus.DebugLevel),
 	}))
 	r.Use(gin.Recovery())
+	r.Use(propagation.Gin())
 	r.Use(genRequestId())
-	r.Use(accessLog())
+	r.Use(accessLog(newAccessLogger(c.AccessLogConfiguration)))
+	r.Use(ginMetrics())
+	if c.Auth != nil {
+		r.Use(c.Auth.Gin())
+	}
 
 	router(r)
 	return r
 }
 
+// genRequestId honours incoming X-Request-Id, otherwise derives one from trace, then echoes and propagates it
 func genRequestId() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		info := tracing.GetRequestInfo(ctx.Request.Context())
-		ctx.Set(keyReqId, info.String())
+		c := ctx.Request.Context()
+		requestId := tracing.NewRequestId(c, ctx.GetHeader(tracing.HeaderRequestId))
+		c = propagation.With(c, tracing.HeaderRequestId, requestId)
+		ctx.Request = ctx.Request.WithContext(tracing.WithRequestId(c, requestId))
+		ctx.Set(keyReqId, requestId)
+		ctx.Header(tracing.HeaderRequestId, requestId)
 		ctx.Next()
 	}
 }
 
-func accessLog() gin.HandlerFunc {
+func accessLog(l *accessLogger) gin.HandlerFunc {
 	pool := &sync.Pool{
-		New: func() interface{} { return &bytes.Buffer{} },
+		New: func() interface{} { return &cappedBuffer{} },
 	}
 
 	return func(ctx *gin.Context) {
-		buf := pool.Get().(*bytes.Buffer)
-		defer pool.Put(buf)
-		buf.Reset()
-
-		reader := ctx.Request.Body
-		defer func() { _ = reader.Close() }()
-
-		ctx.Request.Body = ioutil.NopCloser(io.TeeReader(reader, buf))
 		st := time.Now()
+		req := ctx.Request
+
+		var buf *cappedBuffer
+		if contentType := req.Header.Get("Content-Type"); l.capture(contentType) {
+			buf = pool.Get().(*cappedBuffer)
+			defer pool.Put(buf)
+			buf.Reset()
+			buf.limit, buf.n = l.MaxBodySize, 0
+
+			reader := req.Body
+			defer func() { _ = reader.Close() }()
+			req.Body = ioutil.NopCloser(io.TeeReader(reader, buf))
+		}
 
 		ctx.Set(lf, map[string]interface{}{
 			lfReqId:  ctx.GetString(keyReqId),
@@ -72,19 +127,39 @@ func accessLog() gin.HandlerFunc {
 
 		ctx.Next()
 
+		uri := l.redactUri(req.URL.Path, req.URL.RawQuery)
+		latency := time.Since(st)
 		fields := ctx.GetStringMap(lf)
-		message := fmt.Sprintf("[%v] %v(%v) - %v %v - %v",
-			time.Since(st).Truncate(time.Millisecond),
-			fields[lfStatus], fields[lfCode],
-			ctx.Request.Method, ctx.Request.URL,
-			buf.String())
-
-		logger := logrus.WithFields(fields)
-		if fields[lfCode] == http.StatusOK {
-			logger.Info(message)
-		} else {
-			logger.Warn(message)
+		// Status of responses not rendered by RenderError
+		if code := ctx.Writer.Status(); fields[lfStatus] == errors.CodeToStr(errors.OK) && code != http.StatusOK {
+			fields[lfCode], fields[lfStatus] = code, httpStatus(code)
+		}
+		fields[lfMethod] = req.Method
+		fields[lfPath] = uri
+		fields[lfRoute] = ctx.FullPath()
+		fields[lfIp] = ctx.ClientIP()
+		fields[lfUa] = req.UserAgent()
+		fields[lfTraceId] = traceId(req.Context())
+		fields[lfLatency] = latency.Milliseconds()
+		fields[lfBytesIn] = req.ContentLength
+		fields[lfBytesOut] = ctx.Writer.Size()
+		if buf != nil {
+			if req.ContentLength < 0 {
+				fields[lfBytesIn] = buf.n
+			}
+			l.setBody(fields, req.Header.Get("Content-Type"), buf.Bytes(), buf.truncated())
 		}
+		if fields[lfRoute] == "" {
+			fields[lfRoute] = routeUnmatched
+		}
+		if size, _ := fields[lfBytesOut].(int); size < 0 {
+			fields[lfBytesOut] = 0
+		}
+
+		l.log(fields, fmt.Sprintf("[%v] %v(%v) - %v %v",
+			latency.Truncate(time.Millisecond),
+			fields[lfStatus], fields[lfCode],
+			req.Method, uri))
 	}
 }
 
@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package endpoint
+
+// Upgrade by listener handoff isn't supported
+func watchUpgrade(*Group) {}
+
+func notifyReady(*Group) {}
@@ -0,0 +1,146 @@
+//go:build !windows
+// +build !windows
+
+package endpoint
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+const envReadyFd = "ENDPOINT_READY_FD"
+
+var (
+	// Signal to fork new binary with listeners inherited, nil to disable
+	UpgradeSignal os.Signal = syscall.SIGUSR2
+	// Waiting for child to be ready, continues serving if exceeded
+	UpgradeTimeout = time.Minute
+
+	upgrading int32
+)
+
+// watchUpgrade forks on UpgradeSignal, then shuts down itself once child is ready
+func watchUpgrade(g *Group) {
+	if UpgradeSignal == nil {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, UpgradeSignal)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-g.Done():
+			return
+		case <-ch:
+		}
+
+		if !atomic.CompareAndSwapInt32(&upgrading, 0, 1) {
+			continue
+		}
+		if err := upgrade(); err != nil {
+			logrus.WithError(err).Error("Fail to upgrade, continue serving")
+			atomic.StoreInt32(&upgrading, 0)
+			continue
+		}
+
+		logrus.Info("Upgraded, shutdown to drain")
+		self, _ := os.FindProcess(os.Getpid())
+		_ = self.Signal(syscall.SIGTERM)
+		return
+	}
+}
+
+// upgrade starts current binary with bound listeners, returns once child reports ready
+func upgrade() error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	files := boundFiles()
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envListenFds+"=") && !strings.HasPrefix(kv, envListenPid+"=") &&
+			!strings.HasPrefix(kv, envReadyFd+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env,
+		envListenFds+"="+strconv.Itoa(len(files)),
+		envReadyFd+"="+strconv.Itoa(listenFdsStart+len(files)))
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	err = cmd.Start()
+	_ = w.Close()
+	if err != nil {
+		return err
+	}
+	logrus.WithField("pid", cmd.Process.Pid).Infof("Forked with %d listeners", len(files))
+
+	ready := make(chan error, 1)
+	go func() {
+		// EOF without data if child exits before ready
+		buf := make([]byte, 1)
+		if _, err := r.Read(buf); err != nil {
+			ready <- xerrors.Errorf("child exited before ready: %w", err)
+			return
+		}
+		ready <- nil
+	}()
+
+	select {
+	case err = <-ready:
+	case <-time.After(UpgradeTimeout):
+		err = xerrors.Errorf("child not ready in %v", UpgradeTimeout)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		go func() { _ = cmd.Wait() }()
+		return err
+	}
+	// Child is adopted by init once parent exits
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// notifyReady tells parent once all endpoints are bound
+func notifyReady(g *Group) {
+	fd, err := strconv.Atoi(os.Getenv(envReadyFd))
+	if err != nil {
+		return
+	}
+	_ = os.Unsetenv(envReadyFd)
+
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	select {
+	case <-g.Started():
+		_, _ = f.Write([]byte{1})
+	case <-g.Done():
+	}
+}
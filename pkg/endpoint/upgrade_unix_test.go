@@ -0,0 +1,149 @@
+//go:build !windows
+// +build !windows
+
+package endpoint
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const envTestEndpoints = "ENDPOINT_TEST_INHERIT"
+
+// TestInheritHelper runs in child process, serves its name on each "name=addr" of envTestEndpoints
+func TestInheritHelper(t *testing.T) {
+	spec := os.Getenv(envTestEndpoints)
+	if spec == "" {
+		return
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		name := kv[0]
+		e := Http(name, kv[1], http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name))
+		}))
+		if err := e.(*rest).Listen(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		go func() { _ = e.Run() }()
+	}
+	fmt.Println("ready")
+
+	// Parent closes stdin when done
+	_, _ = io.Copy(ioutil.Discard, os.Stdin)
+	os.Exit(0)
+}
+
+func TestInheritListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Bound by parent, handed over to child as upgrade does
+	addrs := map[string]string{"a": "127.0.0.1:0", "b": "127.0.0.1:0", "c": schemeUnix + filepath.Join(dir, "c.sock")}
+	var spec []string
+	for name, addr := range addrs {
+		lis, err := listen(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lis.Close()
+		if lis.Addr().Network() == "tcp" {
+			addrs[name] = lis.Addr().String()
+		}
+		spec = append(spec, name+"="+addrs[name])
+	}
+	files := boundFiles()
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	testCases := []struct {
+		name      string
+		listenPid string
+		inherited bool
+	}{
+		{"from parent", "", true},
+		// Meant for another process, child binds by itself and fails as parent holds addresses
+		{"other pid", "1", false},
+	}
+
+	for _, c := range testCases {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestInheritHelper$")
+		cmd.Env = append(os.Environ(),
+			envTestEndpoints+"="+strings.Join(spec, ","),
+			fmt.Sprintf("%s=%d", envListenFds, len(files)))
+		if c.listenPid != "" {
+			cmd.Env = append(cmd.Env, envListenPid+"="+c.listenPid)
+		}
+		cmd.ExtraFiles = files
+		stdin, _ := cmd.StdinPipe()
+		stdout, _ := cmd.StdoutPipe()
+		if err = cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		line := make(chan string, 1)
+		go func() {
+			s, _ := bufio.NewReader(stdout).ReadString('\n')
+			line <- strings.TrimSpace(s)
+		}()
+		var out string
+		select {
+		case out = <-line:
+		case <-time.After(5 * time.Second):
+			_ = cmd.Process.Kill()
+			t.Fatalf("%s: child not ready", c.name)
+		}
+
+		if (out == "ready") != c.inherited {
+			_ = cmd.Process.Kill()
+			t.Fatalf("%s: unexpected output of child: %s", c.name, out)
+		}
+		if c.inherited {
+			for name, addr := range addrs {
+				if body := get(t, addr); body != name {
+					t.Fatalf("%s: expected %s served on %s, got %q", c.name, name, addr, body)
+				}
+			}
+		}
+		_ = stdin.Close()
+		_ = cmd.Wait()
+	}
+}
+
+func get(t *testing.T, addr string) string {
+	network, address, _, err := parseAddr(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	}}
+	r, err := client.Get("http://endpoint/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	body, _ := ioutil.ReadAll(r.Body)
+	return string(body)
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderApiKey        = "X-Api-Key"
+
+	// Fiber stores claims in fasthttp user values, which only accepts string keys
+	localsKey = "auth.claims"
+)
+
+var (
+	ErrNoCredential      = xerrors.New("credential required")
+	ErrInvalidCredential = xerrors.New("invalid credential")
+	ErrInsufficientScope = xerrors.New("insufficient scope")
+)
+
+type Claims struct {
+	Subject string
+	Scopes  []string
+	// All claims of token, or metadata of API key
+	Extra map[string]interface{}
+}
+
+func (c *Claims) HasScopes(scopes ...string) bool {
+	for _, want := range scopes {
+		found := false
+		for _, have := range c.Scopes {
+			if have == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type ctxKey struct{}
+
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, ctxKey{}, claims)
+}
+
+func FromContext(ctx context.Context) (*Claims, bool) {
+	if claims, ok := ctx.Value(ctxKey{}).(*Claims); ok {
+		return claims, true
+	}
+	claims, ok := ctx.Value(localsKey).(*Claims)
+	return claims, ok
+}
+
+type Authenticator interface {
+	// Authenticate verifies credential, e.g. bearer token or API key
+	Authenticate(ctx context.Context, credential string) (*Claims, error)
+}
+
+type Policy struct {
+	// Public allows anonymous calls, claims are still attached if credential is valid
+	Public bool
+	// Scopes required, authenticated only if empty
+	Scopes []string
+}
+
+var (
+	Public        = Policy{Public: true}
+	Authenticated = Policy{}
+)
+
+func Scopes(scopes ...string) Policy {
+	return Policy{Scopes: scopes}
+}
+
+type Options struct {
+	// Policies by gRPC full method ("/pkg.Service/Method"), gRPC service ("/pkg.Service/*")
+	// or HTTP route ("GET /users/:id")
+	Policies map[string]Policy
+	// Policy of unlisted methods, default Authenticated
+	Default Policy
+	// Tried in order, the first success wins
+	Authenticators []Authenticator
+	// Allows anonymous gRPC reflection, which lists all services and messages
+	PublicReflection bool
+}
+
+type Auth struct {
+	policies       map[string]Policy
+	fallback       Policy
+	authenticators []Authenticator
+}
+
+func New(opts Options) *Auth {
+	policies := map[string]Policy{
+		"/grpc.health.v1.Health/*": Public,
+	}
+	if opts.PublicReflection {
+		policies["/grpc.reflection.v1alpha.ServerReflection/*"] = Public
+	}
+	for k, v := range opts.Policies {
+		policies[k] = v
+	}
+	return &Auth{
+		policies:       policies,
+		fallback:       opts.Default,
+		authenticators: opts.Authenticators,
+	}
+}
+
+func (a *Auth) policy(key string) Policy {
+	if p, ok := a.policies[key]; ok {
+		return p
+	}
+	// gRPC service wildcard
+	if i := strings.LastIndex(key, "/"); strings.HasPrefix(key, "/") && i > 0 {
+		if p, ok := a.policies[key[:i]+"/*"]; ok {
+			return p
+		}
+	}
+	return a.fallback
+}
+
+// httpPolicy resolves policy by request path, for middlewares running before the route is known
+func (a *Auth) httpPolicy(method, path string) Policy {
+	policy, matched := a.fallback, -1
+	for key, p := range a.policies {
+		i := strings.IndexByte(key, ' ')
+		if i < 0 || key[:i] != method {
+			continue
+		}
+		// The most static segments wins, e.g. "/users/me" over "/users/:id"
+		if n, ok := matchRoute(key[i+1:], path); ok && n > matched {
+			policy, matched = p, n
+		}
+	}
+	return policy
+}
+
+// matchRoute matches path with ":param" and trailing "*" segments of route, returns count of static segments
+func matchRoute(route, path string) (int, bool) {
+	rs := strings.Split(strings.Trim(route, "/"), "/")
+	ps := strings.Split(strings.Trim(path, "/"), "/")
+	static := 0
+	for i, r := range rs {
+		switch {
+		case r == "*" && i == len(rs)-1:
+			return static, true
+		case i >= len(ps):
+			return 0, false
+		case strings.HasPrefix(r, ":"):
+		case r == ps[i]:
+			static++
+		default:
+			return 0, false
+		}
+	}
+	return static, len(rs) == len(ps)
+}
+
+// check returns nil claims for anonymous calls of public methods
+func (a *Auth) check(ctx context.Context, policy Policy, credential string) (*Claims, error) {
+	const op errors.Op = "auth.check"
+
+	if credential == "" {
+		if policy.Public {
+			return nil, nil
+		}
+		return nil, errors.E(op, errors.Unauthenticated, ErrNoCredential)
+	}
+
+	claims, err := a.authenticate(ctx, credential)
+	if err != nil {
+		if policy.Public {
+			return nil, nil
+		}
+		return nil, errors.E(op, errors.Unauthenticated, err)
+	}
+	if !policy.Public && !claims.HasScopes(policy.Scopes...) {
+		return nil, errors.E(op, errors.PermissionDenied, ErrInsufficientScope)
+	}
+	return claims, nil
+}
+
+func (a *Auth) authenticate(ctx context.Context, credential string) (*Claims, error) {
+	err := ErrInvalidCredential
+	for _, authenticator := range a.authenticators {
+		var claims *Claims
+		if claims, err = authenticator.Authenticate(ctx, credential); err == nil {
+			return claims, nil
+		}
+	}
+	return nil, err
+}
+
+// credential from "Authorization: Bearer <token>" or "X-Api-Key: <key>"
+func credential(get func(key string) string) string {
+	const prefix = "bearer "
+	if value := get(HeaderAuthorization); len(value) > len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		return strings.TrimSpace(value[len(prefix):])
+	}
+	return get(HeaderApiKey)
+}
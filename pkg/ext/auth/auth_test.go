@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc/codes"
+
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/server"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+func TestCheck(t *testing.T) {
+	secret := []byte("secret")
+	authenticator, err := NewJWT(JWTOptions{Secret: secret, Issuer: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "u1",
+		"iss":   "test",
+		"scope": "read write",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eternal, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "u1", "iss": "test"}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New(Options{
+		Policies: map[string]Policy{
+			"/app.Service/Public": Public,
+			"/app.Service/Admin":  Scopes("admin"),
+			"/app.Service/Write":  Scopes("write"),
+		},
+		Authenticators: []Authenticator{
+			authenticator,
+			NewAPIKeys(map[string]Claims{"key1": {Subject: "robot"}}),
+		},
+	})
+
+	testCases := []struct {
+		method     string
+		credential string
+		code       codes.Code
+	}{
+		{"/app.Service/Public", "", errors.OK},
+		{"/grpc.health.v1.Health/Check", "", errors.OK},
+		{"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", "", errors.Unauthenticated},
+		{"/app.Service/Get", "", errors.Unauthenticated},
+		{"/app.Service/Get", "broken", errors.Unauthenticated},
+		{"/app.Service/Get", "key1", errors.OK},
+		{"/app.Service/Write", token, errors.OK},
+		{"/app.Service/Write", eternal, errors.Unauthenticated},
+		{"/app.Service/Admin", token, errors.PermissionDenied},
+	}
+
+	for _, c := range testCases {
+		_, err := a.check(context.Background(), a.policy(c.method), c.credential)
+		if code := errors.Code(err); err != nil && code != c.code || err == nil && c.code != errors.OK {
+			t.Fatalf("%s with %q: expected %v got %v", c.method, c.credential, c.code, err)
+		}
+	}
+}
+
+func TestHttp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := New(Options{
+		Policies: map[string]Policy{
+			"GET /users/me":  Public,
+			"GET /users/:id": Scopes("admin"),
+		},
+		Authenticators: []Authenticator{NewAPIKeys(map[string]Claims{"key1": {Subject: "robot"}})},
+	})
+	withAuth := func(c *server.Configuration) { c.Auth = a }
+
+	handlers := map[string]http.Handler{
+		"gin": server.Gin(func(r gin.IRouter) {
+			r.GET("/users/me", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+			r.GET("/users/:id", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+			r.GET("/items", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+		}, withAuth),
+		"fiber": fiberHandler(server.Fiber(func(r *fiber.App) {
+			r.Get("/users/me", func(ctx *fiber.Ctx) error { return nil })
+			r.Get("/users/:id", func(ctx *fiber.Ctx) error { return nil })
+			r.Get("/items", func(ctx *fiber.Ctx) error { return nil })
+		}, withAuth)),
+	}
+
+	testCases := []struct {
+		path   string
+		apiKey string
+		code   int
+	}{
+		{"/users/me", "", http.StatusOK},
+		{"/users/1", "", http.StatusUnauthorized},
+		{"/users/1", "key1", http.StatusForbidden},
+		{"/items", "", http.StatusUnauthorized},
+		{"/items", "key1", http.StatusOK},
+	}
+
+	for name, handler := range handlers {
+		for _, c := range testCases {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.apiKey != "" {
+				req.Header.Set(HeaderApiKey, c.apiKey)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != c.code {
+				t.Fatalf("%s %s with %q: expected %d got %d", name, c.path, c.apiKey, c.code, w.Code)
+			}
+		}
+	}
+}
+
+// fiberHandler serves app through its test entry, as endpoint.FiberHandler imports this package
+func fiberHandler(app *fiber.App) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := app.Test(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	})
+}
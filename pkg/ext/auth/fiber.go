@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+// Fiber resolves policy by matching path with routes of policies, as middlewares run before routing,
+// use it with server.Configuration.Auth or app.Use
+func (a *Auth) Fiber() fiber.Handler {
+	const op errors.Op = "auth.Fiber"
+
+	return func(ctx *fiber.Ctx) error {
+		claims, err := a.check(ctx.Context(), a.httpPolicy(ctx.Method(), ctx.Path()), credential(func(key string) string { return ctx.Get(key) }))
+		if err != nil {
+			return errors.E(op, err)
+		}
+		if claims != nil {
+			ctx.Locals(localsKey, claims)
+		}
+		return ctx.Next()
+	}
+}
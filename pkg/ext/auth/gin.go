@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/server"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+// Gin resolves policy by route template, use it with server.Configuration.Auth or router.Use
+func (a *Auth) Gin() gin.HandlerFunc {
+	const op errors.Op = "auth.Gin"
+
+	return func(ctx *gin.Context) {
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		claims, err := a.check(ctx.Request.Context(), a.policy(route), credential(ctx.GetHeader))
+		if err != nil {
+			server.RenderError(ctx, op, err)
+			return
+		}
+		if claims != nil {
+			ctx.Request = ctx.Request.WithContext(NewContext(ctx.Request.Context(), claims))
+		}
+		ctx.Next()
+	}
+}
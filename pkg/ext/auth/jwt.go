@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/xerrors"
+)
+
+type JWTOptions struct {
+	// Secret of HS256/384/512
+	Secret []byte
+	// PEM encoded RSA public key file of RS256/384/512
+	PublicKeyFile string
+	// JWKS file of RSA keys, selected by "kid" header
+	JWKSFile string
+
+	// Verified if not empty
+	Issuer   string
+	Audience string
+	// Claim of scopes, space separated string or list, default "scope"
+	ScopeClaim string
+}
+
+type jwtAuthenticator struct {
+	opts      JWTOptions
+	publicKey *rsa.PublicKey
+	keys      map[string]*rsa.PublicKey
+}
+
+func NewJWT(opts JWTOptions) (Authenticator, error) {
+	if opts.ScopeClaim == "" {
+		opts.ScopeClaim = "scope"
+	}
+	a := &jwtAuthenticator{opts: opts}
+
+	if opts.PublicKeyFile != "" {
+		data, err := ioutil.ReadFile(opts.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if a.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+			return nil, err
+		}
+	}
+	if opts.JWKSFile != "" {
+		data, err := ioutil.ReadFile(opts.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		if a.keys, err = parseJWKS(data); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Secret) == 0 && a.publicKey == nil && len(a.keys) == 0 {
+		return nil, xerrors.New("no key of jwt")
+	}
+	return a, nil
+}
+
+func (a *jwtAuthenticator) Authenticate(_ context.Context, credential string) (*Claims, error) {
+	mc := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(credential, mc, a.key); err != nil {
+		return nil, xerrors.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	// Parsing verifies exp only if present, tokens never expiring are rejected
+	if !mc.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, xerrors.Errorf("%w: exp required", ErrInvalidCredential)
+	}
+	if a.opts.Issuer != "" && !mc.VerifyIssuer(a.opts.Issuer, true) {
+		return nil, xerrors.Errorf("%w: unexpected issuer", ErrInvalidCredential)
+	}
+	if a.opts.Audience != "" && !mc.VerifyAudience(a.opts.Audience, true) {
+		return nil, xerrors.Errorf("%w: unexpected audience", ErrInvalidCredential)
+	}
+
+	claims := &Claims{Extra: mc}
+	claims.Subject, _ = mc["sub"].(string)
+	switch scopes := mc[a.opts.ScopeClaim].(type) {
+	case string:
+		claims.Scopes = strings.Fields(scopes)
+	case []interface{}:
+		for _, scope := range scopes {
+			if s, ok := scope.(string); ok {
+				claims.Scopes = append(claims.Scopes, s)
+			}
+		}
+	}
+	return claims, nil
+}
+
+// key selects verification key by algorithm, never mixes HMAC and RSA keys
+func (a *jwtAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(a.opts.Secret) == 0 {
+			return nil, xerrors.New("unexpected signing method")
+		}
+		return a.opts.Secret, nil
+	case *jwt.SigningMethodRSA:
+		if kid, _ := token.Header["kid"].(string); kid != "" {
+			if key, ok := a.keys[kid]; ok {
+				return key, nil
+			}
+		}
+		if a.publicKey == nil {
+			return nil, xerrors.New("unknown key")
+		}
+		return a.publicKey, nil
+	default:
+		return nil, xerrors.New("unexpected signing method")
+	}
+}
+
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,239 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	grpc2 "github.com/GotaX/go-server-skeleton/pkg/ext/grpc"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+var ErrOpen = xerrors.New("circuit breaker is open")
+
+var (
+	breakerState = NewGaugeVec(GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "熔断器状态 (0: closed, 1: open, 2: half-open)",
+	}, []string{"name"})
+	breakerTransitions = NewCounterVec(CounterOpts{
+		Name: "circuit_breaker_transitions_total",
+		Help: "熔断器累计状态切换次数",
+	}, []string{"name", "state"})
+	breakerRejected = NewCounterVec(CounterOpts{
+		Name: "circuit_breaker_rejected_total",
+		Help: "熔断器累计拒绝请求数",
+	}, []string{"name"})
+
+	registerBreakerMetrics = &sync.Once{}
+)
+
+type Options struct {
+	// Counting window in closed state, default 10s
+	Window time.Duration
+	// Requests in window before tripping, default 20
+	MinRequests int
+	// Failure ratio to trip, default 0.5
+	FailureRatio float64
+	// Duration of open state before trial, default 5s
+	CoolDown time.Duration
+	// Trial requests in half-open state, default 1
+	HalfOpenRequests int
+	// Decides if error counts as failure,
+	// default Unavailable, ResourceExhausted, Aborted, DeadlineExceeded and Internal
+	IsFailure func(err error) bool
+}
+
+func IsFailure(err error) bool {
+	switch grpc2.Code(err) {
+	case errors.Unavailable, errors.ResourceExhausted, errors.Aborted,
+		errors.DeadlineExceeded, errors.Internal:
+		return true
+	default:
+		return false
+	}
+}
+
+// Group holds breakers by name, e.g. target and method
+type Group struct {
+	opts  Options
+	mu    sync.Mutex
+	items map[string]*Breaker
+}
+
+func NewGroup(opts Options) *Group {
+	registerBreakerMetrics.Do(func() {
+		MustRegister(breakerState, breakerTransitions, breakerRejected)
+	})
+
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Second
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = 20
+	}
+	if opts.FailureRatio <= 0 {
+		opts.FailureRatio = 0.5
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = 5 * time.Second
+	}
+	if opts.HalfOpenRequests <= 0 {
+		opts.HalfOpenRequests = 1
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = IsFailure
+	}
+	return &Group{opts: opts, items: make(map[string]*Breaker)}
+}
+
+func (g *Group) Get(name string) *Breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b, ok := g.items[name]
+	if !ok {
+		b = &Breaker{name: name, opts: g.opts, since: time.Now()}
+		g.items[name] = b
+		breakerState.With(Labels{"name": name}).Set(float64(Closed))
+	}
+	return b
+}
+
+type Breaker struct {
+	name string
+	opts Options
+
+	mu        sync.Mutex
+	state     State
+	since     time.Time
+	gen       uint64
+	total     int
+	failures  int
+	trials    int
+	successes int
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(time.Now())
+	return b.state
+}
+
+// Do calls fn if allowed, result is judged by Options.IsFailure
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err != nil && b.opts.IsFailure(err))
+	return err
+}
+
+// Allow returns errors.Unavailable if open, otherwise caller must report result by done
+func (b *Breaker) Allow() (done func(failed bool), err error) {
+	const op errors.Op = "breaker.Allow"
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(time.Now())
+	if b.state == Open || (b.state == HalfOpen && b.trials >= b.opts.HalfOpenRequests) {
+		breakerRejected.With(Labels{"name": b.name}).Inc()
+		return nil, errors.E(op, errors.Unavailable, xerrors.Errorf("%s: %w", b.name, ErrOpen))
+	}
+	if b.state == HalfOpen {
+		b.trials++
+	}
+
+	gen := b.gen
+	return func(failed bool) { b.done(gen, failed) }, nil
+}
+
+func (b *Breaker) done(gen uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.advance(now)
+	// Result of previous window or state
+	if gen != b.gen {
+		return
+	}
+
+	switch b.state {
+	case Closed:
+		b.total++
+		if failed {
+			b.failures++
+		}
+		if b.total >= b.opts.MinRequests &&
+			float64(b.failures)/float64(b.total) >= b.opts.FailureRatio {
+			b.set(Open, now)
+		}
+	case HalfOpen:
+		if failed {
+			b.set(Open, now)
+			return
+		}
+		if b.successes++; b.successes >= b.opts.HalfOpenRequests {
+			b.set(Closed, now)
+		}
+	}
+}
+
+func (b *Breaker) advance(now time.Time) {
+	switch b.state {
+	case Closed:
+		if now.Sub(b.since) >= b.opts.Window {
+			b.reset(now)
+		}
+	case Open:
+		if now.Sub(b.since) >= b.opts.CoolDown {
+			b.set(HalfOpen, now)
+		}
+	}
+}
+
+func (b *Breaker) set(state State, now time.Time) {
+	logrus.WithField("breaker", b.name).
+		Warnf("State changed: %s -> %s, failures: %d/%d", b.state, state, b.failures, b.total)
+
+	b.state = state
+	b.reset(now)
+
+	breakerState.With(Labels{"name": b.name}).Set(float64(state))
+	breakerTransitions.With(Labels{"name": b.name, "state": state.String()}).Inc()
+}
+
+func (b *Breaker) reset(now time.Time) {
+	b.since = now
+	b.gen++
+	b.total, b.failures, b.trials, b.successes = 0, 0, 0, 0
+}
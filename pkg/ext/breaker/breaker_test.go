@@ -0,0 +1,40 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+func TestBreaker(t *testing.T) {
+	g := NewGroup(Options{MinRequests: 2, CoolDown: 10 * time.Millisecond})
+	b := g.Get("test")
+
+	failure := errors.E(errors.Unavailable, xerrors.New("down"))
+	for i := 0; i < 2; i++ {
+		_ = b.Do(func() error { return failure })
+	}
+	if b.State() != Open {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+
+	called := false
+	err := b.Do(func() error { called = true; return nil })
+	if called || errors.Code(err) != errors.Unavailable || !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected fail fast, got %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open, got %s", b.State())
+	}
+	if err = b.Do(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+}
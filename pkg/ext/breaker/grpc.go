@@ -0,0 +1,27 @@
+package breaker
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryClientInterceptor breaks by target and method, place it inside retry interceptor
+func UnaryClientInterceptor(g *Group) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return g.Get(cc.Target() + method).Do(func() error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// StreamClientInterceptor only judges stream creation
+func StreamClientInterceptor(g *Group) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (stream grpc.ClientStream, err error) {
+		err = g.Get(cc.Target() + method).Do(func() (err error) {
+			stream, err = streamer(ctx, desc, cc, method, opts...)
+			return
+		})
+		return
+	}
+}
@@ -0,0 +1,44 @@
+package breaker
+
+import (
+	"net/http"
+)
+
+type transport struct {
+	group *Group
+	next  http.RoundTripper
+	key   func(r *http.Request) string
+}
+
+// Transport breaks by host, 5xx and 429 responses count as failures.
+// Next defaults to http.DefaultTransport
+func Transport(g *Group, next http.RoundTripper) http.RoundTripper {
+	return TransportWithKey(g, next, func(r *http.Request) string {
+		return r.URL.Host
+	})
+}
+
+// TransportWithKey breaks by key, e.g. host and route template.
+// Raw paths with IDs create a breaker per ID, never use them as key.
+func TransportWithKey(g *Group, next http.RoundTripper, key func(r *http.Request) string) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{group: g, next: next, key: key}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.group.Get(t.key(req)).Allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		// Canceled by caller is not a failure of downstream
+		done(req.Context().Err() == nil)
+		return nil, err
+	}
+	done(resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests)
+	return resp, nil
+}
@@ -0,0 +1,31 @@
+package breaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+func TestTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	g := NewGroup(Options{MinRequests: 2})
+	client := &http.Client{Transport: Transport(g, nil)}
+	for _, path := range []string{"/users/1", "/users/2"} {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	// Paths of the same host share one breaker
+	if _, err := client.Get(ts.URL + "/users/3"); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected open, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+)
+
+// Interval between modification checks of files
+var CheckInterval = 5 * time.Second
+
+// Files of PEM encoded certificates, reloaded once modified
+type Files struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+	// Verify client certificates (mTLS) by CA if set
+	ClientCA string `json:"clientCA"`
+	// Verify client certificates only if given
+	OptionalClientCert bool `json:"optionalClientCert"`
+}
+
+// ServerConfig loads files, then reloads them on handshake after modification
+func (f Files) ServerConfig() (*tls.Config, error) {
+	r := &reloader{files: f}
+	if _, err := r.get(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.get()
+		},
+	}, nil
+}
+
+func (f Files) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(f.Cert, f.Key)
+	if err != nil {
+		return nil, err
+	}
+	c := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if f.ClientCA != "" {
+		data, err := ioutil.ReadFile(f.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, xerrors.Errorf("no certificate found in %s", f.ClientCA)
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		if f.OptionalClientCert {
+			c.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	return c, nil
+}
+
+func (f Files) modTime() (latest time.Time, err error) {
+	for _, name := range []string{f.Cert, f.Key, f.ClientCA} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return
+}
+
+type reloader struct {
+	files Files
+
+	mu      sync.Mutex
+	config  *tls.Config
+	modTime time.Time
+	checked time.Time
+}
+
+// get returns current config, keeps previous one if reload failed
+func (r *reloader) get() (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.config != nil && time.Since(r.checked) < CheckInterval {
+		return r.config, nil
+	}
+	r.checked = time.Now()
+
+	modTime, err := r.files.modTime()
+	if err == nil && r.config != nil && !modTime.After(r.modTime) {
+		return r.config, nil
+	}
+
+	config, err := r.files.load()
+	if err != nil {
+		if r.config == nil {
+			return nil, err
+		}
+		logrus.WithError(err).WithField("cert", r.files.Cert).Warn("Fail to reload certificates")
+		return r.config, nil
+	}
+	if r.config != nil {
+		logrus.WithField("cert", r.files.Cert).Info("Certificates reloaded")
+	}
+	r.config, r.modTime = config, modTime
+	return config, nil
+}
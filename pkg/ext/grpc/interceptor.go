@@ -3,6 +3,7 @@ package grpc
 import (
 	"context"
 	"runtime/debug"
+	"time"
 
 	grpcLogrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	grpcRecovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
@@ -35,11 +36,22 @@ func UnaryClientErrorHandler() grpc.UnaryClientInterceptor {
 	}
 }
 
+// UnaryClientTimeout applies timeout to calls without deadline
+func UnaryClientTimeout(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
 func StreamServerErrorHandler() grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
 		if err = handler(srv, ss); err != nil {
-			ri := tracing.GetRequestInfo(ss.Context())
-			err = errors.Grpc(ri.String(), err)
+			err = errors.Grpc(tracing.RequestId(ss.Context()), err)
 		}
 		return
 	}
@@ -48,8 +60,7 @@ func StreamServerErrorHandler() grpc.StreamServerInterceptor {
 func UnaryServerErrorHandler() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 		if resp, err = handler(ctx, req); err != nil {
-			ri := tracing.GetRequestInfo(ctx)
-			err = errors.Grpc(ri.String(), err)
+			err = errors.Grpc(tracing.RequestId(ctx), err)
 		}
 		return
 	}
@@ -0,0 +1,113 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+// Named apart from go-grpc-prometheus, which registers grpc_client_handled_total without target in its init
+var (
+	clientHandled = NewCounterVec(CounterOpts{
+		Name: "grpc_client_target_handled_total",
+		Help: "gRPC 客户端累计完成调用数",
+	}, []string{"grpc_target", "grpc_type", "grpc_service", "grpc_method", "grpc_code"})
+	clientHandlingSeconds = NewHistogramVec(HistogramOpts{
+		Name:    "grpc_client_target_handling_seconds",
+		Help:    "gRPC 客户端调用耗时",
+		Buckets: DefBuckets,
+	}, []string{"grpc_target", "grpc_type", "grpc_service", "grpc_method"})
+
+	registerClientMetrics = &sync.Once{}
+)
+
+// UnaryClientMetrics records handled count and latency by target and method, place it outermost
+func UnaryClientMetrics() grpc.UnaryClientInterceptor {
+	registerClientMetrics.Do(func() { MustRegister(clientHandled, clientHandlingSeconds) })
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		observeClient(cc.Target(), "unary", method, start, err)
+		return err
+	}
+}
+
+// StreamClientMetrics records when stream ends
+func StreamClientMetrics() grpc.StreamClientInterceptor {
+	registerClientMetrics.Do(func() { MustRegister(clientHandled, clientHandlingSeconds) })
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			observeClient(cc.Target(), streamType(desc), method, start, err)
+			return nil, err
+		}
+		return &monitoredStream{ClientStream: stream, done: func(err error) {
+			observeClient(cc.Target(), streamType(desc), method, start, err)
+		}}, nil
+	}
+}
+
+type monitoredStream struct {
+	grpc.ClientStream
+	once sync.Once
+	done func(err error)
+}
+
+func (s *monitoredStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		s.once.Do(func() { s.done(nil) })
+	} else if err != nil {
+		s.once.Do(func() { s.done(err) })
+	}
+	return err
+}
+
+func observeClient(target, typ, fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	clientHandled.WithLabelValues(target, typ, service, method, Code(err).String()).Inc()
+	clientHandlingSeconds.WithLabelValues(target, typ, service, method).Observe(time.Since(start).Seconds())
+}
+
+// Code of both errors.E and grpc status error
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	code := errors.Code(err)
+	if s, ok := status.FromError(err); ok && code == errors.Unknown {
+		code = s.Code()
+	}
+	return code
+}
+
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.Index(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", "unknown"
+}
+
+func streamType(desc *grpc.StreamDesc) string {
+	switch {
+	case desc.ClientStreams && desc.ServerStreams:
+		return "bidi_stream"
+	case desc.ClientStreams:
+		return "client_stream"
+	default:
+		return "server_stream"
+	}
+}
@@ -0,0 +1,24 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	// Registers default server and client metrics in init, as endpoint/rpc does
+	_ "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientMetrics(t *testing.T) {
+	UnaryClientMetrics()
+	StreamClientMetrics()
+
+	observeClient("app:8082", "unary", "/pkg.Service/Method", time.Now(), status.Error(codes.NotFound, "missing"))
+
+	counter := clientHandled.WithLabelValues("app:8082", "unary", "pkg.Service", "Method", codes.NotFound.String())
+	if actual := testutil.ToFloat64(counter); actual != 1 {
+		t.Fatalf("expected 1 got %v", actual)
+	}
+}
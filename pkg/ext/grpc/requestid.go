@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpcCtxTags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/GotaX/go-server-skeleton/pkg/ext/propagation"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+var mdRequestId = strings.ToLower(tracing.HeaderRequestId)
+
+// UnaryServerRequestId takes x-request-id of metadata or derives one from trace, then echoes it in header
+func UnaryServerRequestId() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, requestId := withRequestId(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(mdRequestId, requestId))
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerRequestId() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, requestId := withRequestId(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(mdRequestId, requestId))
+
+		wrapped := grpcMiddleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+func withRequestId(ctx context.Context) (context.Context, string) {
+	var incoming string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(mdRequestId); len(values) > 0 {
+			incoming = values[0]
+		}
+	}
+	requestId := tracing.NewRequestId(ctx, incoming)
+	grpcCtxTags.Extract(ctx).Set("request_id", requestId)
+	// Outgoing calls carry the same id, place it after propagation interceptors
+	ctx = propagation.With(ctx, mdRequestId, requestId)
+	return tracing.WithRequestId(ctx, requestId), requestId
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+type Checker func(ctx context.Context) error
+
+var (
+	mu       sync.RWMutex
+	checkers = make(map[string]Checker)
+)
+
+func Register(name string, checker Checker) {
+	mu.Lock()
+	checkers[name] = checker
+	mu.Unlock()
+}
+
+func Unregister(name string) {
+	mu.Lock()
+	delete(checkers, name)
+	mu.Unlock()
+}
+
+// Check runs all registered checkers, returns failed ones by name
+func Check(ctx context.Context) map[string]error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	failed := make(map[string]error)
+	for name, checker := range checkers {
+		if err := checker(ctx); err != nil {
+			failed[name] = err
+		}
+	}
+	return failed
+}
+
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		failed := Check(ctx)
+		body := struct {
+			Status string            `json:"status"`
+			Errors map[string]string `json:"errors,omitempty"`
+		}{Status: "UP"}
+
+		code := http.StatusOK
+		if len(failed) > 0 {
+			code = http.StatusServiceUnavailable
+			body.Status = "DOWN"
+			body.Errors = make(map[string]string, len(failed))
+			for name, err := range failed {
+				body.Errors[name] = err.Error()
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
+
+const nameShutdown = "shutdown"
+
+var ErrShuttingDown = xerrors.New("shutting down")
+
+// SetShuttingDown fails checks from now on, so load balancers stop routing new requests
+func SetShuttingDown() {
+	Register(nameShutdown, func(context.Context) error { return ErrShuttingDown })
+}
@@ -0,0 +1,62 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+func (i *Idempotency) Fiber() fiber.Handler {
+	const op errors.Op = "idempotency.Fiber"
+
+	return func(ctx *fiber.Ctx) error {
+		key := ctx.Get(HeaderKey)
+		if key == "" || !isMutating(ctx.Method()) {
+			return ctx.Next()
+		}
+
+		// Route of middleware is not the matched one before Next, key by path instead
+		route := ctx.Method() + " " + ctx.Path()
+		key = storeKey(route, key)
+		fp := fingerprint(route, ctx.Body())
+
+		rec, err := i.begin(ctx.Context(), key, fp)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		if rec != nil {
+			for k, v := range rec.Header {
+				ctx.Set(k, v)
+			}
+			return ctx.Status(rec.Status).Send(rec.Body)
+		}
+
+		err = ctx.Next()
+
+		bg := context.Background()
+		entry := logrus.WithField("key", key)
+
+		// Errors are rendered later by error handler, only successful responses are replayed
+		status := ctx.Response().StatusCode()
+		if err != nil || status >= fiber.StatusBadRequest {
+			if errRelease := i.release(bg, key); errRelease != nil {
+				entry.WithError(errRelease).Warn("Fail to release idempotency key")
+			}
+			return err
+		}
+
+		rec = &Record{
+			Fingerprint: fp,
+			Status:      status,
+			Header:      map[string]string{fiber.HeaderContentType: string(ctx.Response().Header.ContentType())},
+			Body:        append([]byte(nil), ctx.Response().Body()...),
+		}
+		if errSave := i.complete(bg, key, *rec); errSave != nil {
+			entry.WithError(errSave).Warn("Fail to save idempotent response")
+		}
+		return nil
+	}
+}
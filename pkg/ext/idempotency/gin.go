@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/server"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+type bodyWriter struct {
+	gin.ResponseWriter
+	buf *bytes.Buffer
+}
+
+func (w bodyWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w bodyWriter) WriteString(s string) (int, error) {
+	w.buf.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (i *Idempotency) Gin() gin.HandlerFunc {
+	const op errors.Op = "idempotency.Gin"
+
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(HeaderKey)
+		if key == "" || !isMutating(ctx.Request.Method) {
+			ctx.Next()
+			return
+		}
+
+		body, err := ioutil.ReadAll(ctx.Request.Body)
+		if err != nil {
+			server.RenderError(ctx, op, errors.E(errors.InvalidArgument, err))
+			return
+		}
+		ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		route := ctx.Request.Method + " " + ctx.Request.URL.Path
+		key = storeKey(route, key)
+		fp := fingerprint(route, body)
+
+		rec, err := i.begin(ctx.Request.Context(), key, fp)
+		if err != nil {
+			server.RenderError(ctx, op, err)
+			return
+		}
+		if rec != nil {
+			for k, v := range rec.Header {
+				ctx.Header(k, v)
+			}
+			ctx.Data(rec.Status, rec.Header["Content-Type"], rec.Body)
+			ctx.Abort()
+			return
+		}
+
+		w := bodyWriter{ResponseWriter: ctx.Writer, buf: &bytes.Buffer{}}
+		ctx.Writer = w
+
+		ctx.Next()
+
+		// Request is detached from client, finish bookkeeping even if client gone
+		bg := context.Background()
+		entry := logrus.WithField("key", key)
+
+		// Only successful responses are replayed, let client retry failed ones as Fiber and gRPC do
+		if status := w.Status(); status >= http.StatusBadRequest {
+			if err = i.release(bg, key); err != nil {
+				entry.WithError(err).Warn("Fail to release idempotency key")
+			}
+			return
+		}
+
+		rec = &Record{
+			Fingerprint: fp,
+			Status:      w.Status(),
+			Header:      map[string]string{"Content-Type": w.Header().Get("Content-Type")},
+			Body:        w.buf.Bytes(),
+		}
+		if err = i.complete(bg, key, *rec); err != nil {
+			entry.WithError(err).Warn("Fail to save idempotent response")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+func (i *Idempotency) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	const op errors.Op = "idempotency.UnaryServerInterceptor"
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(MetadataKey)
+		msg, ok := req.(proto.Message)
+		if len(values) == 0 || values[0] == "" || !ok {
+			return handler(ctx, req)
+		}
+
+		buf := proto.NewBuffer(nil)
+		buf.SetDeterministic(true)
+		if err := buf.Marshal(msg); err != nil {
+			return nil, errors.E(op, errors.InvalidArgument, err)
+		}
+
+		key := storeKey(info.FullMethod, values[0])
+		fp := fingerprint(info.FullMethod, buf.Bytes())
+
+		rec, err := i.begin(ctx, key, fp)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		if rec != nil {
+			return decodeReply(rec.Body)
+		}
+
+		resp, err := handler(ctx, req)
+
+		bg := context.Background()
+		entry := logrus.WithField("key", key)
+
+		if err != nil {
+			if errRelease := i.release(bg, key); errRelease != nil {
+				entry.WithError(errRelease).Warn("Fail to release idempotency key")
+			}
+			return resp, err
+		}
+
+		body, errEncode := encodeReply(resp)
+		if errEncode != nil {
+			entry.WithError(errEncode).Warn("Fail to encode idempotent response")
+			return resp, err
+		}
+		if errSave := i.complete(bg, key, Record{Fingerprint: fp, Body: body}); errSave != nil {
+			entry.WithError(errSave).Warn("Fail to save idempotent response")
+		}
+		return resp, err
+	}
+}
+
+func encodeReply(resp interface{}) ([]byte, error) {
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil, xerrors.Errorf("reply is not proto.Message: %T", resp)
+	}
+	packed, err := ptypes.MarshalAny(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(packed)
+}
+
+func decodeReply(data []byte) (interface{}, error) {
+	const op errors.Op = "idempotency.decodeReply"
+
+	var (
+		packed = &any.Any{}
+		reply  = &ptypes.DynamicAny{}
+	)
+	if err := proto.Unmarshal(data, packed); err != nil {
+		return nil, errors.E(op, errors.DataLoss, err)
+	}
+	if err := ptypes.UnmarshalAny(packed, reply); err != nil {
+		return nil, errors.E(op, errors.DataLoss, err)
+	}
+	return reply.Message, nil
+}
@@ -0,0 +1,95 @@
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint/server"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+func TestHttp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := map[string]int{}
+	handlers := map[string]http.Handler{
+		"gin": server.Gin(func(r gin.IRouter) {
+			i := New(Options{Store: memStore{}})
+			r.Use(i.Gin())
+			r.POST("/orders/:id", func(ctx *gin.Context) {
+				calls["gin "+ctx.Request.URL.Path]++
+				ctx.String(http.StatusCreated, ctx.Param("id"))
+			})
+			r.POST("/fail", func(ctx *gin.Context) {
+				calls["gin /fail"]++
+				ctx.Status(http.StatusBadRequest)
+			})
+		}),
+		"fiber": fiberHandler(server.Fiber(func(r *fiber.App) {
+			i := New(Options{Store: memStore{}})
+			r.Use(i.Fiber())
+			r.Post("/orders/:id", func(ctx *fiber.Ctx) error {
+				calls["fiber "+ctx.Path()]++
+				return ctx.Status(http.StatusCreated).SendString(ctx.Params("id"))
+			})
+			r.Post("/fail", func(ctx *fiber.Ctx) error {
+				calls["fiber /fail"]++
+				return errors.E(errors.InvalidArgument, xerrors.New("bad request"))
+			})
+		})),
+	}
+
+	testCases := []struct {
+		path  string
+		code  int
+		body  string
+		calls int
+	}{
+		{"/orders/1", http.StatusCreated, "1", 1},
+		// Same key on another path is a different request
+		{"/orders/2", http.StatusCreated, "2", 1},
+		{"/orders/1", http.StatusCreated, "1", 1},
+		{"/fail", http.StatusBadRequest, "", 1},
+		// Failed responses are not replayed
+		{"/fail", http.StatusBadRequest, "", 2},
+	}
+
+	for name, handler := range handlers {
+		for _, c := range testCases {
+			req := httptest.NewRequest(http.MethodPost, c.path, strings.NewReader(`{}`))
+			req.Header.Set(HeaderKey, "k1")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != c.code || c.body != "" && w.Body.String() != c.body {
+				t.Fatalf("%s %s: expected %d %q got %d %q", name, c.path, c.code, c.body, w.Code, w.Body.String())
+			}
+			if n := calls[name+" "+c.path]; n != c.calls {
+				t.Fatalf("%s %s: expected %d calls got %d", name, c.path, c.calls, n)
+			}
+		}
+	}
+}
+
+// fiberHandler serves app through its test entry, as endpoint.FiberHandler imports this package
+func fiberHandler(app *fiber.App) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := app.Test(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	})
+}
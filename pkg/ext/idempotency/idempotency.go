@@ -0,0 +1,121 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+const (
+	HeaderKey   = "Idempotency-Key"
+	MetadataKey = "idempotency-key"
+)
+
+var (
+	ErrInFlight         = xerrors.New("request with same idempotency key is in flight")
+	ErrMismatchedParams = xerrors.New("idempotency key reused with different request")
+)
+
+type Record struct {
+	Fingerprint string            `json:"fingerprint"`
+	Done        bool              `json:"done"`
+	Status      int               `json:"status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+type Store interface {
+	// Acquire saves rec under key if absent, otherwise returns the existing record
+	Acquire(ctx context.Context, key string, rec Record, ttl time.Duration) (*Record, error)
+	Save(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+type Options struct {
+	Store Store
+	// TTL of completed responses
+	TTL time.Duration
+	// TTL of in flight marker, protect keys from crashed requests
+	LockTTL time.Duration
+}
+
+type Idempotency struct {
+	store   Store
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+func New(opts Options) *Idempotency {
+	i := &Idempotency{
+		store:   opts.Store,
+		ttl:     opts.TTL,
+		lockTTL: opts.LockTTL,
+	}
+	if i.ttl <= 0 {
+		i.ttl = 24 * time.Hour
+	}
+	if i.lockTTL <= 0 {
+		i.lockTTL = time.Minute
+	}
+	return i
+}
+
+// begin returns stored record for replay, or nil if caller should handle the request
+func (i *Idempotency) begin(ctx context.Context, key, fingerprint string) (*Record, error) {
+	const op errors.Op = "idempotency.begin"
+
+	rec, err := i.store.Acquire(ctx, key, Record{Fingerprint: fingerprint}, i.lockTTL)
+	switch {
+	case xerrors.Is(err, ErrInFlight):
+		// Key raced with another request in store
+		return nil, errors.E(op, errors.Aborted, err)
+	case err != nil:
+		return nil, errors.E(op, errors.Unavailable, err)
+	case rec == nil:
+		return nil, nil
+	case rec.Fingerprint != fingerprint:
+		return nil, errors.E(op, errors.FailedPrecondition, errors.FailedPreconditionError{
+			Type:        "IDEMPOTENCY_KEY",
+			Subject:     key,
+			Description: ErrMismatchedParams.Error(),
+		})
+	case !rec.Done:
+		return nil, errors.E(op, errors.Aborted, ErrInFlight)
+	default:
+		return rec, nil
+	}
+}
+
+func (i *Idempotency) complete(ctx context.Context, key string, rec Record) error {
+	rec.Done = true
+	return i.store.Save(ctx, key, rec, i.ttl)
+}
+
+func (i *Idempotency) release(ctx context.Context, key string) error {
+	return i.store.Release(ctx, key)
+}
+
+func storeKey(route, key string) string {
+	return route + ":" + key
+}
+
+func fingerprint(route string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(route))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
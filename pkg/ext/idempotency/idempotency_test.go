@@ -0,0 +1,63 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+type memStore map[string]Record
+
+func (s memStore) Acquire(_ context.Context, key string, rec Record, _ time.Duration) (*Record, error) {
+	if existing, ok := s[key]; ok {
+		return &existing, nil
+	}
+	s[key] = rec
+	return nil, nil
+}
+
+func (s memStore) Save(_ context.Context, key string, rec Record, _ time.Duration) error {
+	s[key] = rec
+	return nil
+}
+
+func (s memStore) Release(_ context.Context, key string) error {
+	delete(s, key)
+	return nil
+}
+
+func TestBegin(t *testing.T) {
+	ctx := context.Background()
+	i := New(Options{Store: memStore{}})
+
+	fp := fingerprint("POST /orders", []byte(`{"id":1}`))
+	if rec, err := i.begin(ctx, "k1", fp); rec != nil || err != nil {
+		t.Fatalf("first request should proceed, got %v, %v", rec, err)
+	}
+
+	if _, err := i.begin(ctx, "k1", fp); errors.Code(err) != errors.Aborted {
+		t.Fatalf("concurrent duplicate: expected %v got %v", errors.Aborted, err)
+	}
+
+	other := fingerprint("POST /orders", []byte(`{"id":2}`))
+	if _, err := i.begin(ctx, "k1", other); errors.Code(err) != errors.FailedPrecondition {
+		t.Fatalf("mismatched body: expected %v got %v", errors.FailedPrecondition, err)
+	}
+
+	if err := i.complete(ctx, "k1", Record{Fingerprint: fp, Status: 201, Body: []byte("ok")}); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := i.begin(ctx, "k1", fp)
+	if err != nil || rec == nil || rec.Status != 201 || string(rec.Body) != "ok" {
+		t.Fatalf("replay: expected stored response, got %v, %v", rec, err)
+	}
+
+	if err = i.release(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if rec, err = i.begin(ctx, "k1", other); rec != nil || err != nil {
+		t.Fatalf("released key should proceed, got %v, %v", rec, err)
+	}
+}
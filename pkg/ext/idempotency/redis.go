@@ -0,0 +1,65 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	driver "github.com/go-redis/redis/v8"
+)
+
+type redisStore struct {
+	client *driver.Client
+	prefix string
+}
+
+func NewRedisStore(client *driver.Client, prefix string) Store {
+	if prefix == "" {
+		prefix = "idempotency:"
+	}
+	return &redisStore{client: client, prefix: prefix}
+}
+
+func (s *redisStore) Acquire(ctx context.Context, key string, rec Record, ttl time.Duration) (*Record, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retry once when existing key expired between SETNX and GET
+	for i := 0; i < 2; i++ {
+		ok, err := s.client.SetNX(ctx, s.prefix+key, data, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return nil, nil
+		}
+
+		existing, err := s.client.Get(ctx, s.prefix+key).Bytes()
+		if err == driver.Nil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var stored Record
+		if err = json.Unmarshal(existing, &stored); err != nil {
+			return nil, err
+		}
+		return &stored, nil
+	}
+	return nil, ErrInFlight
+}
+
+func (s *redisStore) Save(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.prefix+key, data, ttl).Err()
+}
+
+func (s *redisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
@@ -0,0 +1,100 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/rds"
+)
+
+// Table schema (MySQL):
+//
+//	CREATE TABLE idempotency_keys (
+//	  idem_key   VARCHAR(255) NOT NULL PRIMARY KEY,
+//	  record     BLOB         NOT NULL,
+//	  expires_at TIMESTAMP    NOT NULL
+//	);
+type sqlStore struct {
+	db      *sql.DB
+	table   string
+	dialect rds.Dialect
+}
+
+func NewSQLStore(db *sql.DB, table string, dialect rds.Dialect) Store {
+	if table == "" {
+		table = "idempotency_keys"
+	}
+	return &sqlStore{db: db, table: table, dialect: dialect}
+}
+
+func (s *sqlStore) Acquire(ctx context.Context, key string, rec Record, ttl time.Duration) (*Record, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	insert := s.query("INSERT INTO %s (idem_key, record, expires_at) VALUES (?, ?, ?)")
+	_, errInsert := s.db.ExecContext(ctx, insert, key, data, time.Now().Add(ttl))
+	if errInsert == nil {
+		return nil, nil
+	}
+
+	var (
+		stored  []byte
+		rawTime interface{}
+	)
+	get := s.query("SELECT record, expires_at FROM %s WHERE idem_key = ?")
+	switch err = s.db.QueryRowContext(ctx, get, key).Scan(&stored, &rawTime); {
+	case err == sql.ErrNoRows:
+		// Not a conflict, report the original error
+		return nil, errInsert
+	case err != nil:
+		return nil, err
+	}
+	expiresAt, err := rds.ScanTime(rawTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(expiresAt) {
+		del := s.query("DELETE FROM %s WHERE idem_key = ? AND expires_at = ?")
+		if _, err = s.db.ExecContext(ctx, del, key, expiresAt); err != nil {
+			return nil, err
+		}
+		if _, err = s.db.ExecContext(ctx, insert, key, data, time.Now().Add(ttl)); err != nil {
+			// Another request took the expired key first
+			return nil, xerrors.Errorf("%v: %w", err, ErrInFlight)
+		}
+		return nil, nil
+	}
+
+	var existing Record
+	if err = json.Unmarshal(stored, &existing); err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (s *sqlStore) Save(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	update := s.query("UPDATE %s SET record = ?, expires_at = ? WHERE idem_key = ?")
+	_, err = s.db.ExecContext(ctx, update, data, time.Now().Add(ttl), key)
+	return err
+}
+
+func (s *sqlStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.query("DELETE FROM %s WHERE idem_key = ?"), key)
+	return err
+}
+
+func (s *sqlStore) query(format string) string {
+	return s.dialect.Rebind(fmt.Sprintf(format, s.table))
+}
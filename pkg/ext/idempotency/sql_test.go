@@ -0,0 +1,115 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/rds"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+func TestSQLStore(t *testing.T) {
+	const (
+		live    = "2099-01-01 00:00:00"
+		expired = "2000-01-01 00:00:00"
+	)
+	duplicate := xerrors.New("duplicate entry")
+	stored := []byte(`{"fingerprint":"fp","done":true,"status":201}`)
+
+	testCases := []struct {
+		name     string
+		expect   func(mock sqlmock.Sqlmock)
+		existing bool
+	}{
+		{"acquired", func(mock sqlmock.Sqlmock) {
+			mock.ExpectExec("INSERT INTO idempotency_keys").
+				WithArgs("k", sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}, false},
+		{"replayed", func(mock sqlmock.Sqlmock) {
+			mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(duplicate)
+			mock.ExpectQuery("SELECT record, expires_at FROM idempotency_keys WHERE idem_key = \\?").
+				WithArgs("k").
+				WillReturnRows(sqlmock.NewRows([]string{"record", "expires_at"}).AddRow(stored, []byte(live)))
+		}, true},
+		{"expired", func(mock sqlmock.Sqlmock) {
+			mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(duplicate)
+			mock.ExpectQuery("SELECT record, expires_at FROM idempotency_keys").
+				WillReturnRows(sqlmock.NewRows([]string{"record", "expires_at"}).AddRow(stored, []byte(expired)))
+			mock.ExpectExec("DELETE FROM idempotency_keys WHERE idem_key = \\? AND expires_at = \\?").
+				WithArgs("k", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 1))
+		}, false},
+	}
+
+	for _, c := range testCases {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.expect(mock)
+
+		rec, err := NewSQLStore(db, "", rds.MySQL).Acquire(context.Background(), "k", Record{Fingerprint: "fp"}, time.Minute)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if (rec != nil) != c.existing || rec != nil && (!rec.Done || rec.Status != 201) {
+			t.Fatalf("%s: unexpected record %+v", c.name, rec)
+		}
+		if err = mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		_ = db.Close()
+	}
+}
+
+func TestSQLStorePostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE keys SET record = \\$1, expires_at = \\$2 WHERE idem_key = \\$3").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "k").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM keys WHERE idem_key = \\$1").
+		WithArgs("k").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := NewSQLStore(db, "keys", rds.Postgres)
+	if err = s.Save(context.Background(), "k", Record{Done: true}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Release(context.Background(), "k"); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLStoreRaced(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	duplicate := xerrors.New("duplicate entry")
+	mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(duplicate)
+	mock.ExpectQuery("SELECT record, expires_at FROM idempotency_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"record", "expires_at"}).AddRow([]byte(`{}`), time.Unix(0, 0)))
+	mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(duplicate)
+
+	i := New(Options{Store: NewSQLStore(db, "", rds.MySQL)})
+	if _, err = i.begin(context.Background(), "k", "fp"); errors.Code(err) != errors.Aborted {
+		t.Fatalf("expected %v got %v", errors.Aborted, err)
+	}
+}
@@ -0,0 +1,61 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	driver "github.com/go-redis/redis/v8"
+	"github.com/rs/xid"
+)
+
+type Elector interface {
+	// Acquire takes or renews leadership, returns true if current process is leader
+	Acquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+var (
+	renewScript = driver.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	releaseScript = driver.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+type redisElector struct {
+	client *driver.Client
+	key    string
+	token  string
+	ttl    time.Duration
+}
+
+// NewRedisElector elects by a lease key, ttl should be longer than the acquire interval
+func NewRedisElector(client *driver.Client, key string, ttl time.Duration) Elector {
+	return &redisElector{
+		client: client,
+		key:    key,
+		token:  xid.New().String(),
+		ttl:    ttl,
+	}
+}
+
+func (e *redisElector) Acquire(ctx context.Context) (bool, error) {
+	renewed, err := renewScript.Run(ctx, e.client, []string{e.key}, e.token, e.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	if renewed == 1 {
+		return true, nil
+	}
+	return e.client.SetNX(ctx, e.key, e.token, e.ttl).Result()
+}
+
+func (e *redisElector) Release(ctx context.Context) error {
+	return releaseScript.Run(ctx, e.client, []string{e.key}, e.token).Err()
+}
@@ -0,0 +1,51 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	driver "github.com/go-redis/redis/v8"
+)
+
+func TestRedisElector(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	client := driver.NewClient(&driver.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	a := NewRedisElector(client, "leader", time.Second)
+	b := NewRedisElector(client, "leader", time.Second)
+
+	steps := []struct {
+		name     string
+		elector  Elector
+		action   func()
+		expected bool
+	}{
+		{"a acquires", a, nil, true},
+		{"b waits", b, nil, false},
+		{"a renews", a, nil, true},
+		{"b takes expired lease", b, func() { s.FastForward(2 * time.Second) }, true},
+		{"a lost", a, nil, false},
+		{"a takes released lease", a, func() { _ = b.Release(ctx) }, true},
+	}
+	for _, step := range steps {
+		if step.action != nil {
+			step.action()
+		}
+		leading, err := step.elector.Acquire(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if leading != step.expected {
+			t.Fatalf("%s: expected %v got %v", step.name, step.expected, leading)
+		}
+	}
+}
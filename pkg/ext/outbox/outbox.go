@@ -0,0 +1,188 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/amqp"
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/rds"
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+// Table schema (MySQL):
+//
+//	CREATE TABLE outbox (
+//	  id            BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+//	  aggregate_key VARCHAR(255) NOT NULL,
+//	  exchange      VARCHAR(255) NOT NULL,
+//	  routing_key   VARCHAR(255) NOT NULL,
+//	  type          VARCHAR(255) NOT NULL,
+//	  headers       TEXT         NOT NULL,
+//	  content_type  VARCHAR(255) NOT NULL,
+//	  payload       BLOB         NOT NULL,
+//	  created_at    TIMESTAMP    NOT NULL,
+//	  sent_at       TIMESTAMP    NULL,
+//	  INDEX idx_outbox_pending (sent_at, id)
+//	);
+type Event struct {
+	// Events with same aggregate key are published in insertion order
+	AggregateKey string
+	Exchange     string
+	Key          string
+	Type         string
+	Headers      map[string]string
+	// Encoded by outbox codec
+	Body interface{}
+}
+
+type Outbox struct {
+	db      *sql.DB
+	table   string
+	dialect rds.Dialect
+	codec   amqp.Codec
+}
+
+func New(db *sql.DB, table string, dialect rds.Dialect, codec amqp.Codec) *Outbox {
+	if table == "" {
+		table = "outbox"
+	}
+	if codec == nil {
+		codec = amqp.JSON
+	}
+	return &Outbox{db: db, table: table, dialect: dialect, codec: codec}
+}
+
+// Add inserts events inside caller's transaction, they are relayed after commit
+func (o *Outbox) Add(ctx context.Context, tx *sql.Tx, events ...Event) error {
+	const op errors.Op = "outbox.Add"
+
+	insert := o.query("INSERT INTO %s " +
+		"(aggregate_key, exchange, routing_key, type, headers, content_type, payload, created_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+
+	// Keep trace of the producer, relay continues it
+	traced := traceHeaders(ctx)
+
+	for _, e := range events {
+		payload, err := o.codec.Marshal(e.Body)
+		if err != nil {
+			return errors.E(op, errors.InvalidArgument, err)
+		}
+
+		headers := make(map[string]string, len(e.Headers)+len(traced))
+		for k, v := range traced {
+			headers[k] = v
+		}
+		for k, v := range e.Headers {
+			headers[k] = v
+		}
+		encoded, err := json.Marshal(headers)
+		if err != nil {
+			return errors.E(op, errors.InvalidArgument, err)
+		}
+
+		if _, err = tx.ExecContext(ctx, insert,
+			e.AggregateKey, e.Exchange, e.Key, e.Type,
+			string(encoded), o.codec.ContentType(), payload, time.Now()); err != nil {
+			return errors.E(op, err)
+		}
+	}
+	return nil
+}
+
+type row struct {
+	id           int64
+	aggregateKey string
+	exchange     string
+	key          string
+	typ          string
+	headers      map[string]string
+	contentType  string
+	payload      []byte
+}
+
+func (o *Outbox) pending(ctx context.Context, limit int) ([]row, error) {
+	rows, err := o.db.QueryContext(ctx, o.query("SELECT "+
+		"id, aggregate_key, exchange, routing_key, type, headers, content_type, payload "+
+		"FROM %s WHERE sent_at IS NULL ORDER BY id LIMIT ?"), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []row
+	for rows.Next() {
+		var (
+			r       row
+			headers string
+		)
+		if err = rows.Scan(&r.id, &r.aggregateKey, &r.exchange, &r.key, &r.typ,
+			&headers, &r.contentType, &r.payload); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(headers), &r.headers); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func (o *Outbox) markSent(ctx context.Context, id int64) error {
+	_, err := o.db.ExecContext(ctx, o.query("UPDATE %s SET sent_at = ? WHERE id = ?"), time.Now(), id)
+	return err
+}
+
+func (o *Outbox) cleanup(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := o.db.ExecContext(ctx,
+		o.query("DELETE FROM %s WHERE sent_at IS NOT NULL AND sent_at < ?"),
+		time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// lag returns number of pending events and age of the oldest one
+func (o *Outbox) lag(ctx context.Context) (int64, time.Duration, error) {
+	var (
+		count int64
+		// Aggregated column is []byte without parseTime=true in MySQL DSN
+		rawTime interface{}
+	)
+	if err := o.db.QueryRowContext(ctx,
+		o.query("SELECT COUNT(*), MIN(created_at) FROM %s WHERE sent_at IS NULL")).
+		Scan(&count, &rawTime); err != nil {
+		return 0, 0, err
+	}
+	oldest, err := rds.ScanTime(rawTime)
+	if err != nil || oldest.IsZero() {
+		return count, 0, err
+	}
+	return count, time.Since(oldest), nil
+}
+
+func (o *Outbox) query(format string) string {
+	return o.dialect.Rebind(fmt.Sprintf(format, o.table))
+}
+
+func traceHeaders(ctx context.Context) map[string]string {
+	header := http.Header{}
+	if span := trace.FromContext(ctx); span != nil {
+		tracing.SpanContextToHeader(span.SpanContext(), header)
+	}
+	header.Set(tracing.HeaderRequestId, tracing.RequestId(ctx))
+
+	m := make(map[string]string, len(header))
+	for k := range header {
+		m[k] = header.Get(k)
+	}
+	return m
+}
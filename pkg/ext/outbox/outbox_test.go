@@ -0,0 +1,210 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/amqp"
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/rds"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+var columns = []string{"id", "aggregate_key", "exchange", "routing_key", "type", "headers", "content_type", "payload"}
+
+type fakePublisher struct {
+	fail       map[string]bool
+	published  []string
+	requestIds []string
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, msg amqp.Message) error {
+	if p.fail[msg.MessageId] {
+		return xerrors.New("nack")
+	}
+	p.published = append(p.published, msg.MessageId)
+	p.requestIds = append(p.requestIds, tracing.RequestId(ctx))
+	return nil
+}
+
+type fakeElector struct {
+	leases   int
+	acquired int
+}
+
+func (e *fakeElector) Acquire(ctx context.Context) (bool, error) {
+	e.acquired++
+	e.leases--
+	return e.leases >= 0, nil
+}
+
+func (e *fakeElector) Release(ctx context.Context) error {
+	return nil
+}
+
+func newTestRelay(t *testing.T, p *fakePublisher, leases int) (*relay, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &relay{
+		outbox:    New(db, "", rds.MySQL, nil),
+		publisher: p,
+		elector:   &fakeElector{leases: leases},
+		opts:      RelayOptions{BatchSize: 10},
+		entry:     logrus.WithField("name", "test"),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}, mock
+}
+
+func TestAdd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("order-1", "orders", "order.created", "created", sqlmock.AnyArg(), "application/json", []byte(`{"id":1}`), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ctx := tracing.WithRequestId(context.Background(), "r1")
+	tx, _ := db.Begin()
+	if err = New(db, "", rds.MySQL, nil).Add(ctx, tx, Event{
+		AggregateKey: "order-1", Exchange: "orders", Key: "order.created", Type: "created",
+		Body: map[string]int{"id": 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayOrdering(t *testing.T) {
+	p := &fakePublisher{fail: map[string]bool{"1": true}}
+	r, mock := newTestRelay(t, p, 10)
+
+	mock.ExpectQuery("SELECT (.+) FROM outbox WHERE sent_at IS NULL ORDER BY id LIMIT").
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "a", "ex", "k", "t", `{"X-Request-Id":"r1"}`, "application/json", []byte("{}")).
+			AddRow(2, "a", "ex", "k", "t", `{"X-Request-Id":"r2"}`, "application/json", []byte("{}")).
+			AddRow(3, "b", "ex", "k", "t", `{"X-Request-Id":"r3"}`, "application/json", []byte("{}")))
+	mock.ExpectExec("UPDATE outbox SET sent_at").
+		WithArgs(sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r.relay(context.Background())
+
+	// Event 2 waits for event 1 of the same aggregate
+	if len(p.published) != 1 || p.published[0] != "3" {
+		t.Fatalf("unexpected published: %v", p.published)
+	}
+	if p.requestIds[0] != "r3" {
+		t.Fatalf("expected request id of producer, got %s", p.requestIds[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayRound(t *testing.T) {
+	p := &fakePublisher{}
+	r, mock := newTestRelay(t, p, 1)
+	r.opts.Retention = time.Hour
+
+	mock.ExpectQuery("SELECT (.+) FROM outbox").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "a", "ex", "k", "t", `{}`, "application/json", []byte("{}")).
+			AddRow(2, "b", "ex", "k", "t", `{}`, "application/json", []byte("{}")))
+	mock.ExpectExec("UPDATE outbox SET sent_at").
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE outbox SET sent_at").
+		WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COUNT").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "min"}).AddRow(0, nil))
+	mock.ExpectExec("DELETE FROM outbox").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	r.round(context.Background())
+
+	// Lease is checked once for the whole batch
+	if elector := r.elector.(*fakeElector); elector.acquired != 1 {
+		t.Fatalf("expected 1 acquisition, got %d", elector.acquired)
+	}
+	if len(p.published) != 2 {
+		t.Fatalf("unexpected published: %v", p.published)
+	}
+
+	// Lease lost, nothing is queried
+	r.round(context.Background())
+	if r.isLeader || len(p.published) != 2 {
+		t.Fatalf("published without leadership: %v", p.published)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayStop(t *testing.T) {
+	r, _ := newTestRelay(t, &fakePublisher{}, 0)
+
+	stopped := make(chan struct{})
+	go func() {
+		_ = r.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop blocks without Run")
+	}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLag(t *testing.T) {
+	oldest := time.Now().UTC().Add(-time.Minute)
+	testCases := []struct {
+		name   string
+		oldest interface{}
+		lag    bool
+	}{
+		{"time", oldest, true},
+		// MySQL without parseTime=true
+		{"text", []byte(oldest.Format("2006-01-02 15:04:05")), true},
+		{"empty", nil, false},
+	}
+
+	for _, c := range testCases {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\), MIN\\(created_at\\) FROM outbox WHERE sent_at IS NULL").
+			WillReturnRows(sqlmock.NewRows([]string{"count", "min"}).AddRow(2, c.oldest))
+
+		count, lag, err := New(db, "", rds.MySQL, nil).lag(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if count != 2 || c.lag && lag < time.Minute-time.Second || !c.lag && lag != 0 {
+			t.Fatalf("%s: unexpected lag: %d %v", c.name, count, lag)
+		}
+		_ = db.Close()
+	}
+}
@@ -0,0 +1,263 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	driver "github.com/streadway/amqp"
+	"go.opencensus.io/trace"
+
+	"github.com/GotaX/go-server-skeleton/pkg/cfg/amqp"
+	"github.com/GotaX/go-server-skeleton/pkg/endpoint"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/leader"
+	"github.com/GotaX/go-server-skeleton/pkg/ext/tracing"
+)
+
+var (
+	outboxPending = NewGaugeVec(GaugeOpts{
+		Name: "outbox_pending",
+		Help: "Outbox 待发送事件数",
+	}, []string{"table"})
+	outboxLag = NewGaugeVec(GaugeOpts{
+		Name: "outbox_lag_seconds",
+		Help: "Outbox 最早待发送事件的延迟",
+	}, []string{"table"})
+	outboxRelayed = NewCounterVec(CounterOpts{
+		Name: "outbox_relayed_total",
+		Help: "Outbox 累计发送事件数",
+	}, []string{"table"})
+	outboxFailed = NewCounterVec(CounterOpts{
+		Name: "outbox_failed_total",
+		Help: "Outbox 累计发送失败数",
+	}, []string{"table"})
+
+	registerOutboxMetrics = &sync.Once{}
+)
+
+type RelayOptions struct {
+	// Poll interval, default 1s
+	Interval time.Duration
+	// Max events per poll, default 100. Leadership is checked once per batch, keep it short within the lease
+	BatchSize int
+	// Keep sent events for, default 7 days
+	Retention time.Duration
+	// Cleanup interval, default 1h
+	CleanupInterval time.Duration
+	// Publisher confirm timeout, default 5s
+	ConfirmTimeout time.Duration
+}
+
+// publisher is implemented by *amqp.Publisher
+type publisher interface {
+	Publish(ctx context.Context, msg amqp.Message) error
+}
+
+type relay struct {
+	name      string
+	outbox    *Outbox
+	publisher publisher
+	elector   leader.Elector
+	opts      RelayOptions
+	entry     *logrus.Entry
+
+	isLeader    bool
+	lastCleanup time.Time
+
+	mu      sync.Mutex
+	running bool
+	stopped bool
+	once    sync.Once
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Relay publishes pending events while holding leadership
+func (o *Outbox) Relay(name string, conn *amqp.Connection, elector leader.Elector, opts RelayOptions) endpoint.Endpoint {
+	registerOutboxMetrics.Do(func() {
+		MustRegister(outboxPending, outboxLag, outboxRelayed, outboxFailed)
+	})
+
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 7 * 24 * time.Hour
+	}
+	if opts.CleanupInterval <= 0 {
+		opts.CleanupInterval = time.Hour
+	}
+
+	return &relay{
+		name:      name,
+		outbox:    o,
+		publisher: conn.Publisher(amqp.Bytes, opts.ConfirmTimeout),
+		elector:   elector,
+		opts:      opts,
+		entry:     logrus.WithFields(logrus.Fields{"name": name, "table": o.table}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (r *relay) Name() string {
+	return fmt.Sprintf("Outbox (%s), %s", r.outbox.table, r.name)
+}
+
+func (r *relay) Run() error {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running = true
+	r.mu.Unlock()
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			if r.isLeader {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				if err := r.elector.Release(ctx); err != nil {
+					r.entry.WithError(err).Warn("Fail to release leadership")
+				}
+				cancel()
+			}
+			return nil
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), r.opts.Interval*10)
+		r.round(ctx)
+		cancel()
+	}
+}
+
+// round relays a batch while holding leadership, which is checked once per batch
+func (r *relay) round(ctx context.Context) {
+	leading, err := r.elector.Acquire(ctx)
+	if err != nil {
+		r.entry.WithError(err).Warn("Fail to acquire leadership")
+	}
+	if leading != r.isLeader {
+		r.entry.Infof("Leadership changed, leader: %v", leading)
+		r.isLeader = leading
+	}
+	if !r.isLeader {
+		return
+	}
+
+	r.relay(ctx)
+	r.measure(ctx)
+
+	if time.Since(r.lastCleanup) > r.opts.CleanupInterval {
+		r.lastCleanup = time.Now()
+		if n, err := r.outbox.cleanup(ctx, r.opts.Retention); err != nil {
+			r.entry.WithError(err).Warn("Fail to cleanup")
+		} else if n > 0 {
+			r.entry.Debugf("Cleanup %d events", n)
+		}
+	}
+}
+
+// Stop waits for running batch, returns at once if Run is never called
+func (r *relay) Stop() error {
+	r.mu.Lock()
+	r.stopped = true
+	running := r.running
+	r.mu.Unlock()
+
+	r.once.Do(func() { close(r.stop) })
+	if running {
+		<-r.done
+	}
+	return nil
+}
+
+func (r *relay) relay(ctx context.Context) {
+	rows, err := r.outbox.pending(ctx, r.opts.BatchSize)
+	if err != nil {
+		r.entry.WithError(err).Warn("Fail to load pending events")
+		return
+	}
+
+	labels := Labels{"table": r.outbox.table}
+
+	// Once an event failed, later events of same aggregate wait for next round
+	blocked := make(map[string]bool)
+	for _, row := range rows {
+		if blocked[row.aggregateKey] {
+			continue
+		}
+		// Batch exceeds its deadline, lease may have expired as well
+		if err = ctx.Err(); err != nil {
+			r.entry.WithError(err).Warn("Stop relaying batch")
+			return
+		}
+
+		if err = r.publish(ctx, row); err == nil {
+			err = r.outbox.markSent(ctx, row.id)
+		}
+		if err != nil {
+			blocked[row.aggregateKey] = true
+			outboxFailed.With(labels).Inc()
+			r.entry.WithError(err).WithField("id", row.id).Warn("Fail to relay event")
+			continue
+		}
+		outboxRelayed.With(labels).Inc()
+	}
+}
+
+func (r *relay) publish(ctx context.Context, row row) error {
+	header := http.Header{}
+	headers := driver.Table{}
+	for k, v := range row.headers {
+		header.Set(k, v)
+		headers[k] = v
+	}
+
+	// Publisher overwrites request id by the one of ctx
+	if requestId := header.Get(tracing.HeaderRequestId); requestId != "" {
+		ctx = tracing.WithRequestId(ctx, requestId)
+	}
+	// Continue trace of the producer
+	if sc, ok := tracing.SpanContextFromHeader(header); ok {
+		var span *trace.Span
+		ctx, span = trace.StartSpanWithRemoteParent(ctx, "outbox.relay", sc)
+		defer span.End()
+	}
+
+	return r.publisher.Publish(ctx, amqp.Message{
+		Exchange:    row.exchange,
+		Key:         row.key,
+		Mandatory:   true,
+		MessageId:   strconv.FormatInt(row.id, 10),
+		Type:        row.typ,
+		ContentType: row.contentType,
+		Headers:     headers,
+		Body:        row.payload,
+	})
+}
+
+func (r *relay) measure(ctx context.Context) {
+	count, lag, err := r.outbox.lag(ctx)
+	if err != nil {
+		r.entry.WithError(err).Warn("Fail to measure lag")
+		return
+	}
+	labels := Labels{"table": r.outbox.table}
+	outboxPending.With(labels).Set(float64(count))
+	outboxLag.With(labels).Set(lag.Seconds())
+}
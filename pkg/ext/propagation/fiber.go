@@ -0,0 +1,20 @@
+package propagation
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// Fiber captures values into c.Context(), which is passed to outbound calls
+func Fiber() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if values := capture(func(key string) string { return c.Get(key) }); len(values) > 0 {
+			c.Locals(localsKey, values)
+		}
+		return c.Next()
+	}
+}
+
+// FiberWith sets key in addition to values of c, see With
+func FiberWith(c *fiber.Ctx, key, value string) {
+	c.Locals(localsKey, FromContext(c.Context()).with(key, value))
+}
@@ -0,0 +1,14 @@
+package propagation
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if values := FromHeader(c.Request.Header); len(values) > 0 {
+			c.Request = c.Request.WithContext(NewContext(c.Request.Context(), values))
+		}
+		c.Next()
+	}
+}
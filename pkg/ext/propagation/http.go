@@ -0,0 +1,26 @@
+package propagation
+
+import (
+	"net/http"
+)
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// Transport injects values of request context into headers, next defaults to http.DefaultTransport
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if values := FromContext(req.Context()).To(req.URL.Host); len(values) > 0 {
+		// RoundTripper should not modify request
+		req = req.Clone(req.Context())
+		ToHeader(values, req.Header)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,153 @@
+package propagation
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/metadata"
+)
+
+type Config struct {
+	// Keys propagated from inbound to all outbound calls
+	Keys []string `json:"keys"`
+	// Keys propagated only to listed destinations, e.g. {"authorization": ["api.internal", "dns:///user:8082"]}.
+	// Destination is host (with or without port) of HTTP request, or target of gRPC client.
+	Restricted map[string][]string `json:"restricted"`
+}
+
+func DefaultConfig() Config {
+	return Config{Keys: []string{"x-request-id", "x-tenant-id", "accept-language", "x-canary"}}
+}
+
+var config atomic.Value
+
+// Configure replaces config, call it before serving
+func Configure(c Config) {
+	normalized := Config{Restricted: make(map[string][]string, len(c.Restricted))}
+	for _, k := range c.Keys {
+		normalized.Keys = append(normalized.Keys, strings.ToLower(k))
+	}
+	for k, destinations := range c.Restricted {
+		normalized.Restricted[strings.ToLower(k)] = destinations
+	}
+	config.Store(normalized)
+}
+
+func current() Config {
+	if c, ok := config.Load().(Config); ok {
+		return c
+	}
+	return DefaultConfig()
+}
+
+// Fiber carries values by fasthttp user values, which only accepts string keys
+const localsKey = "propagation.values"
+
+type ctxKey struct{}
+
+// Values of allowed keys captured from inbound request
+type Values map[string]string
+
+func NewContext(ctx context.Context, values Values) context.Context {
+	return context.WithValue(ctx, ctxKey{}, values)
+}
+
+func FromContext(ctx context.Context) Values {
+	if values, ok := ctx.Value(ctxKey{}).(Values); ok {
+		return values
+	}
+	if values, ok := ctx.Value(localsKey).(Values); ok {
+		return values
+	}
+	return nil
+}
+
+// With returns ctx carrying key in addition, which is propagated even if not configured
+func With(ctx context.Context, key, value string) context.Context {
+	return NewContext(ctx, FromContext(ctx).with(key, value))
+}
+
+func (values Values) with(key, value string) Values {
+	copied := make(Values, len(values)+1)
+	for k, v := range values {
+		copied[k] = v
+	}
+	copied[strings.ToLower(key)] = value
+	return copied
+}
+
+func FromHeader(header http.Header) Values {
+	return capture(header.Get)
+}
+
+func FromMetadata(md metadata.MD) Values {
+	return capture(func(key string) string { return strings.Join(md.Get(key), ",") })
+}
+
+// ToHeader sets values absent in header
+func ToHeader(values Values, header http.Header) {
+	for k, v := range values {
+		if header.Get(k) == "" {
+			header.Set(k, v)
+		}
+	}
+}
+
+// ToMetadata sets values absent in md
+func ToMetadata(values Values, md metadata.MD) {
+	for k, v := range values {
+		if len(md.Get(k)) == 0 {
+			md.Set(k, v)
+		}
+	}
+}
+
+// To returns values allowed to propagate to destination
+func (values Values) To(destination string) Values {
+	restricted := current().Restricted
+	if len(restricted) == 0 {
+		return values
+	}
+
+	allowed := make(Values, len(values))
+	for k, v := range values {
+		if destinations, ok := restricted[k]; !ok || matchDestination(destinations, destination) {
+			allowed[k] = v
+		}
+	}
+	return allowed
+}
+
+func matchDestination(destinations []string, destination string) bool {
+	if destination == "" {
+		return false
+	}
+	for _, d := range destinations {
+		if d == destination {
+			return true
+		}
+		// Host without port
+		if i := strings.LastIndex(destination, ":"); i > 0 && d == destination[:i] {
+			return true
+		}
+	}
+	return false
+}
+
+func capture(get func(key string) string) Values {
+	c := current()
+	values := make(Values)
+	for _, k := range c.Keys {
+		if v := get(k); v != "" {
+			values[k] = v
+		}
+	}
+	for k := range c.Restricted {
+		if v := get(k); v != "" {
+			values[k] = v
+		}
+	}
+	return values
+}
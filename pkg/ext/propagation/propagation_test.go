@@ -0,0 +1,61 @@
+package propagation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPropagation(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Tenant-Id", "t1")
+	header.Set("X-Request-Id", "r1")
+	header.Set("Cookie", "secret")
+
+	ctx := NewContext(context.Background(), FromHeader(header))
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "r2")
+	md, _ := metadata.FromOutgoingContext(outgoing(ctx, "app:8082"))
+
+	expected := map[string]string{"x-tenant-id": "t1", "x-request-id": "r2", "cookie": ""}
+	for k, v := range expected {
+		if got := md.Get(k); (v == "" && len(got) > 0) || (v != "" && (len(got) != 1 || got[0] != v)) {
+			t.Fatalf("unexpected %s: %v", k, got)
+		}
+	}
+}
+
+func TestRestricted(t *testing.T) {
+	defer Configure(DefaultConfig())
+	c := DefaultConfig()
+	c.Restricted = map[string][]string{"Authorization": {"api.internal", "dns:///user:8082"}}
+	Configure(c)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer t")
+	header.Set("X-Tenant-Id", "t1")
+	values := FromHeader(header)
+
+	testCases := []struct {
+		destination string
+		expected    string
+	}{
+		{"api.internal:443", "Bearer t"},
+		{"api.internal", "Bearer t"},
+		{"dns:///user:8082", "Bearer t"},
+		{"api.example.com", ""},
+		{"", ""},
+	}
+	for _, c := range testCases {
+		allowed := values.To(c.destination)
+		if allowed["authorization"] != c.expected || allowed["x-tenant-id"] != "t1" {
+			t.Fatalf("%s: unexpected %v", c.destination, allowed)
+		}
+	}
+
+	Configure(DefaultConfig())
+	if _, ok := FromHeader(header)["authorization"]; ok {
+		t.Fatal("authorization captured by default")
+	}
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+// Locals key of server span, also found by context.Value of fasthttp.RequestCtx
+const localsKey = "tracing.span"
+
+// Fiber extracts span context by Propagation, then starts a server span named by route
+func Fiber() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := http.Header{}
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			header.Add(string(key), string(value))
+		})
+
+		name := c.Path()
+		opt := trace.WithSpanKind(trace.SpanKindServer)
+		var span *trace.Span
+		if sc, ok := SpanContextFromHeader(header); ok {
+			_, span = trace.StartSpanWithRemoteParent(context.Background(), name, sc, opt)
+		} else {
+			_, span = trace.StartSpan(context.Background(), name, opt)
+		}
+		defer span.End()
+
+		span.AddAttributes(
+			trace.StringAttribute(ochttp.MethodAttribute, c.Method()),
+			trace.StringAttribute(ochttp.PathAttribute, c.Path()),
+			trace.StringAttribute(ochttp.HostAttribute, c.Hostname()),
+			trace.StringAttribute(ochttp.UserAgentAttribute, c.Get(fiber.HeaderUserAgent)))
+		c.Locals(localsKey, span)
+
+		err := c.Next()
+
+		// Matched route is known after routing
+		span.SetName(c.Route().Path)
+
+		if err != nil {
+			code := errors.Code(err)
+			span.AddAttributes(trace.Int64Attribute(ochttp.StatusCodeAttribute, int64(errors.CodeToHttp(code))))
+			span.SetStatus(trace.Status{Code: int32(code), Message: err.Error()})
+		} else {
+			status := c.Response().StatusCode()
+			span.AddAttributes(trace.Int64Attribute(ochttp.StatusCodeAttribute, int64(status)))
+			span.SetStatus(ochttp.TraceStatus(status, ""))
+		}
+		return err
+	}
+}
+
+// FiberContext returns context carrying span of c, use it for outgoing calls
+func FiberContext(c *fiber.Ctx) context.Context {
+	ctx := context.Context(c.Context())
+	if span, ok := c.Locals(localsKey).(*trace.Span); ok {
+		ctx = trace.NewContext(ctx, span)
+	}
+	return ctx
+}
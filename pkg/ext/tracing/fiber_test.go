@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opencensus.io/trace"
+)
+
+type recordExporter struct {
+	spans []*trace.SpanData
+}
+
+func (e *recordExporter) ExportSpan(s *trace.SpanData) {
+	e.spans = append(e.spans, s)
+}
+
+func TestFiber(t *testing.T) {
+	const traceId = "463ac35c9f6413ad48485a3953bb6124"
+
+	exporter := &recordExporter{}
+	trace.RegisterExporter(exporter)
+	defer trace.UnregisterExporter(exporter)
+
+	app := fiber.New()
+	app.Use(Fiber())
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		return c.SendString(GetRequestInfo(c.Context()).RootID)
+	})
+
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("X-B3-TraceId", traceId)
+	req.Header.Set("X-B3-SpanId", "a2fb4a1d1a96d312")
+	req.Header.Set("X-B3-Sampled", "1")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != traceId {
+		t.Fatalf("expected trace id %s, got %s", traceId, body)
+	}
+	if len(exporter.spans) != 1 || exporter.spans[0].Name != "/users/:id" {
+		t.Fatalf("expected span named by route, got %v", exporter.spans)
+	}
+}
@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+)
+
+// Same default as ochttp when Propagation not configured
+var defaultFormat = &b3.HTTPFormat{}
+
+func httpFormat() propagation.HTTPFormat {
+	if Propagation != nil {
+		return Propagation
+	}
+	return defaultFormat
+}
+
+// SpanContextFromHeader extracts span context from non http carriers, e.g. message headers
+func SpanContextFromHeader(header http.Header) (trace.SpanContext, bool) {
+	return httpFormat().SpanContextFromRequest(&http.Request{Header: header})
+}
+
+// SpanContextToHeader injects span context into non http carriers, e.g. message headers
+func SpanContextToHeader(sc trace.SpanContext, header http.Header) {
+	httpFormat().SpanContextToRequest(sc, &http.Request{Header: header})
+}
@@ -8,6 +8,8 @@ import (
 	"go.opencensus.io/trace"
 )
 
+const HeaderRequestId = "X-Request-Id"
+
 type RequestInfo struct {
 	RootID string
 	ID     string
@@ -18,7 +20,12 @@ func (info RequestInfo) String() string {
 }
 
 func GetRequestInfo(ctx context.Context) (info RequestInfo) {
-	if span := trace.FromContext(ctx); span != nil {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		// Context of fiber
+		span, _ = ctx.Value(localsKey).(*trace.Span)
+	}
+	if span != nil {
 		sc := span.SpanContext()
 		info.RootID = sc.TraceID.String()
 		info.ID = sc.SpanID.String()
@@ -29,3 +36,48 @@ func GetRequestInfo(ctx context.Context) (info RequestInfo) {
 	}
 	return
 }
+
+// Locals key of request id in fiber, also found by context.Value of fasthttp.RequestCtx
+const LocalsRequestId = "tracing.request_id"
+
+type requestIdKey struct{}
+
+const maxRequestIdLen = 128
+
+// NewRequestId returns incoming id if valid, otherwise trace id of ctx, which is the same across hops
+func NewRequestId(ctx context.Context, incoming string) string {
+	if validRequestId(incoming) {
+		return incoming
+	}
+	return GetRequestInfo(ctx).RootID
+}
+
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestId returns id stored by WithRequestId or fiber locals, otherwise derives one from trace
+func RequestId(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIdKey{}).(string); ok {
+		return id
+	}
+	if id, ok := ctx.Value(LocalsRequestId).(string); ok {
+		return id
+	}
+	return GetRequestInfo(ctx).RootID
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestNewRequestId(t *testing.T) {
+	testCases := []struct {
+		incoming string
+		honoured bool
+	}{
+		{"abc-123", true},
+		{"9b2e0c1a.4f:1_2", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{strings.Repeat("a", maxRequestIdLen+1), false},
+	}
+
+	for _, c := range testCases {
+		id := NewRequestId(context.Background(), c.incoming)
+		if (id == c.incoming) != c.honoured {
+			t.Fatalf("%q: expected honoured %v, got %q", c.incoming, c.honoured, id)
+		}
+	}
+
+	ctx := WithRequestId(context.Background(), "stored")
+	if id := RequestId(ctx); id != "stored" {
+		t.Fatalf("expected stored id, got %q", id)
+	}
+
+	ctx, span := trace.StartSpan(context.Background(), "test")
+	defer span.End()
+	if id, traceId := NewRequestId(ctx, ""), span.SpanContext().TraceID.String(); id != traceId || RequestId(ctx) != traceId {
+		t.Fatalf("expected trace id %s, got %s", traceId, id)
+	}
+}
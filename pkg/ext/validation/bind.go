@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+// BindGin binds request by method and content type, then validates v
+func BindGin(ctx *gin.Context, v interface{}) error {
+	const op errors.Op = "validation.BindGin"
+
+	err := ctx.ShouldBind(v)
+	switch err.(type) {
+	case nil:
+		return nil
+	case validator.ValidationErrors:
+		return errors.E(op, invalid(err))
+	default:
+		return errors.E(op, errors.InvalidArgument, err)
+	}
+}
+
+// BindFiber binds body by content type, then validates v
+func BindFiber(ctx *fiber.Ctx, v interface{}) error {
+	const op errors.Op = "validation.BindFiber"
+
+	if err := ctx.BodyParser(v); err != nil {
+		return errors.E(op, errors.InvalidArgument, err)
+	}
+	return errors.E(op, Struct(v))
+}
@@ -0,0 +1,131 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+// Error of a field, implemented by protoc-gen-validate
+type fieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// Error of ValidateAll, implemented by protoc-gen-validate
+type multiError interface {
+	AllErrors() []error
+}
+
+// Name fields of the validator shared with gin by json tags, before any struct is cached
+func init() {
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(jsonName)
+	}
+}
+
+// Message validates by ValidateAll() or Validate() if implemented
+func Message(msg interface{}) error {
+	const op errors.Op = "validation.Message"
+
+	var err error
+	switch v := msg.(type) {
+	case interface{ ValidateAll() error }:
+		err = v.ValidateAll()
+	case interface{ Validate() error }:
+		err = v.Validate()
+	}
+	return errors.E(op, invalid(err))
+}
+
+// Struct validates "binding" tags by the validator shared with gin
+func Struct(v interface{}) error {
+	const op errors.Op = "validation.Struct"
+
+	return errors.E(op, invalid(binding.Validator.ValidateStruct(v)))
+}
+
+func invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.E(errors.InvalidArgument, errors.NewBadRequestError(Violations(err)...))
+}
+
+// Violations flattens validation errors, nested fields are joined by "."
+func Violations(err error) []errdetails.BadRequest_FieldViolation {
+	var violations []errdetails.BadRequest_FieldViolation
+	collect("", err, &violations)
+	return violations
+}
+
+func collect(prefix string, err error, violations *[]errdetails.BadRequest_FieldViolation) {
+	switch e := err.(type) {
+	case multiError:
+		for _, sub := range e.AllErrors() {
+			collect(prefix, sub, violations)
+		}
+	case fieldError:
+		field := join(prefix, e.Field())
+		// Error of embedded message
+		switch e.Cause().(type) {
+		case multiError, fieldError:
+			collect(field, e.Cause(), violations)
+			return
+		}
+		*violations = append(*violations, errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: e.Reason(),
+		})
+	case validator.ValidationErrors:
+		for _, fe := range e {
+			// Namespace starts with type name of the struct
+			namespace := fe.Namespace()
+			if i := strings.Index(namespace, "."); i >= 0 {
+				namespace = namespace[i+1:]
+			}
+			*violations = append(*violations, errdetails.BadRequest_FieldViolation{
+				Field:       join(prefix, namespace),
+				Description: describe(fe),
+			})
+		}
+	default:
+		*violations = append(*violations, errdetails.BadRequest_FieldViolation{
+			Field:       prefix,
+			Description: err.Error(),
+		})
+	}
+}
+
+func describe(fe validator.FieldError) string {
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed on the '%s=%s' rule", fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+}
+
+func join(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+	return prefix + "." + field
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
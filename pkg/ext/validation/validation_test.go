@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	"github.com/GotaX/go-server-skeleton/pkg/errors"
+)
+
+type pgvError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e pgvError) Error() string  { return e.reason }
+func (e pgvError) Field() string  { return e.field }
+func (e pgvError) Reason() string { return e.reason }
+func (e pgvError) Cause() error   { return e.cause }
+
+type pgvMultiError []error
+
+func (m pgvMultiError) Error() string      { return "multiple errors" }
+func (m pgvMultiError) AllErrors() []error { return m }
+
+type message struct{ err error }
+
+func (m message) ValidateAll() error { return m.err }
+
+type address struct {
+	City string `json:"city" binding:"required"`
+}
+
+type user struct {
+	Name    string  `json:"name" binding:"required,min=3"`
+	Address address `json:"address"`
+}
+
+func TestMessage(t *testing.T) {
+	err := Message(message{err: pgvMultiError{
+		pgvError{field: "name", reason: "value length must be at least 3 runes"},
+		pgvError{field: "address", reason: "embedded message failed validation",
+			cause: pgvError{field: "city", reason: "value is required"}},
+	}})
+	assertViolations(t, err, "name", "address.city")
+}
+
+func TestStruct(t *testing.T) {
+	err := Struct(&user{Name: "ab"})
+	assertViolations(t, err, "name", "address.city")
+}
+
+func assertViolations(t *testing.T, err error, fields ...string) {
+	if errors.Code(err) != errors.InvalidArgument {
+		t.Fatalf("expected invalid argument, got %v", err)
+	}
+	var bad *errors.BadRequestError
+	if !xerrors.As(err, &bad) || len(bad.FieldViolations) != len(fields) {
+		t.Fatalf("unexpected violations: %v", err)
+	}
+	for i, field := range fields {
+		if bad.FieldViolations[i].Field != field {
+			t.Fatalf("expected %s got %s", field, bad.FieldViolations[i].Field)
+		}
+	}
+}